@@ -1,22 +1,79 @@
 package chitamcputils
 
 import (
+	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
 )
 
+// DefaultSpanFlushTimeout bounds the wait for the spans of each request when ServerOptions.SpanFlushTimeout is zero
+const DefaultSpanFlushTimeout = 2 * time.Second
+
 type ServerOptions struct {
 	Name        string
 	Version     string
 	Description string
 	Debug       bool
+	// Logger overrides the default stdout logger of the server
+	Logger *slog.Logger
+	// SensitiveArguments lists tool argument names that must never be logged
+	SensitiveArguments []string
+	// MetricsPath enables Prometheus metrics served on the given path
+	MetricsPath string
+	// OpenAPIPath serves the OpenAPI document of the tools on the given path, it lists the REST endpoints of the
+	// tools so it has no operations without RESTBasePath
+	OpenAPIPath string
+	// RESTBasePath exposes every tool as a plain JSON endpoint under {RESTBasePath}/tools/, "/" for the root.
+	// REST is off when empty, and only ReadOnly tools accept GET.
+	RESTBasePath string
+	// Tracer enables tracing of requests and tool calls
+	Tracer *mcp.Tracer
+	// SpanFlushTimeout is how long each request waits for its spans to be exported, as the lambda may be frozen
+	// once it answers. DefaultSpanFlushTimeout when zero, negative leaves spans to the background export.
+	SpanFlushTimeout time.Duration
+	// CORS restricts the browser origins allowed to call the server, only loopback origins when nil
+	CORS *mcp.CORSPolicy
+	// Streaming declares that the lambda is deployed with a streamed response body
+	Streaming bool
+	// KeepAlive is the interval of heartbeats sent on streamed tool responses, zero disables them
+	KeepAlive time.Duration
+	// Limits bounds request sizes, argument nesting and tool result sizes. The lambda runtime decodes the payload
+	// before the server sees it, so MaxRequestBytes only saves the work done after decoding.
+	Limits mcp.Limits
+	// RejectUnknownSessions answers session ids the instance does not know with 404 so clients initialize again.
+	// By default they get a new session, as each cold start and each lambda instance starts with no sessions.
+	RejectUnknownSessions bool
 }
 
 // CreateMCPServer initializes and configures an MCP server for our hour service
 func CreateMCPServer(options ServerOptions) *mcp.Server {
 	server := mcp.NewServer(options.Name, options.Version, options.Description)
 	server.SetDebug(options.Debug)
+	server.SetLogger(options.Logger)
+	server.SetSensitiveArguments(options.SensitiveArguments...)
+	if options.MetricsPath != "" {
+		server.EnableMetrics(options.MetricsPath)
+	}
+	if options.OpenAPIPath != "" {
+		server.EnableOpenAPI(options.OpenAPIPath)
+	}
+	if options.RESTBasePath != "" {
+		server.EnableREST(options.RESTBasePath)
+	}
+	server.SetTracer(options.Tracer)
+	if options.SpanFlushTimeout == 0 {
+		options.SpanFlushTimeout = DefaultSpanFlushTimeout
+	}
+	server.SetSpanFlushTimeout(options.SpanFlushTimeout)
+	server.SetStreaming(options.Streaming)
+	server.SetKeepAlive(options.KeepAlive)
+	server.SetLimits(options.Limits)
+	server.SetStatelessSessions(!options.RejectUnknownSessions)
+	if options.CORS != nil {
+		server.SetCORSPolicy(*options.CORS)
+	}
 	return server
 }
 
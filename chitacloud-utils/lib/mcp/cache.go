@@ -0,0 +1,210 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheEntries is the capacity of the LRU store of a ToolCache without MaxEntries
+const DefaultCacheEntries = 1000
+
+// CacheStore keeps tool results until they expire, implementations must be safe for concurrent use
+type CacheStore interface {
+	Get(key string) (any, bool)
+	Set(key string, value any, ttl time.Duration)
+	// DeletePrefix removes every entry whose key starts with prefix
+	DeletePrefix(prefix string)
+}
+
+// ToolCache enables result caching for an idempotent tool. Results are keyed by the canonical JSON of the
+// arguments and, with PerCaller, by the caller identity. Errors are never cached, and cached results are
+// shared between calls so handlers must not modify them.
+type ToolCache struct {
+	// TTL is how long a result is served from the cache
+	TTL time.Duration
+	// MaxEntries is the capacity of the default LRU store, DefaultCacheEntries when zero
+	MaxEntries int
+	// PerCaller keeps separate entries per caller, it requires Identity
+	PerCaller bool
+	// Identity resolves the caller of a request when PerCaller is set. It must return an identity the server
+	// authenticated, e.g. the subject of a verified token, as callers sharing it share cached results.
+	Identity func(r *http.Request) string
+	// Store overrides the in-memory LRU store, e.g. to share results between instances
+	Store CacheStore
+
+	once sync.Once
+}
+
+func (c *ToolCache) store() CacheStore {
+	c.once.Do(func() {
+		if c.Store == nil {
+			c.Store = NewLRUCache(c.MaxEntries)
+		}
+	})
+	return c.Store
+}
+
+// key derives the cache key of a call, tool and arguments come first so they can be invalidated by prefix
+func (c *ToolCache) key(r *http.Request, tool string, arguments map[string]any) (string, error) {
+	prefix, err := cacheKeyPrefix(tool, arguments)
+	if err != nil {
+		return "", err
+	}
+	if !c.PerCaller {
+		return prefix, nil
+	}
+	identity := c.Identity(r)
+	if identity == "" {
+		return "", errors.New("cache identity of the caller is empty")
+	}
+	return prefix + identity, nil
+}
+
+// cacheKeyPrefix identifies a tool, and the arguments of a call when they are not nil.
+// encoding/json sorts map keys, so the encoded arguments are canonical.
+func cacheKeyPrefix(tool string, arguments map[string]any) (string, error) {
+	if arguments == nil {
+		return tool + "\x00", nil
+	}
+	encoded, err := json.Marshal(arguments)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return tool + "\x00" + hex.EncodeToString(sum[:]) + "\x00", nil
+}
+
+type cacheContextKey struct{}
+
+// cacheDirective lets a running handler opt out of caching its result
+type cacheDirective struct {
+	mu   sync.Mutex
+	skip bool
+}
+
+// SkipCache keeps the result of the running tool call out of the cache, e.g. for partial or degraded answers
+func SkipCache(ctx context.Context) {
+	if directive, ok := ctx.Value(cacheContextKey{}).(*cacheDirective); ok {
+		directive.mu.Lock()
+		directive.skip = true
+		directive.mu.Unlock()
+	}
+}
+
+func (d *cacheDirective) skipped() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.skip
+}
+
+// bypassCache reports whether the caller asked for a fresh result with Cache-Control: no-cache
+func bypassCache(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache")
+}
+
+// InvalidateCache drops the cached results of a tool for the given arguments, or all of them when arguments is nil
+func (s *Server) InvalidateCache(tool string, arguments map[string]any) error {
+	description := s.FindTool(tool)
+	if description == nil {
+		return ErrToolNotFound
+	}
+	if description.Cache == nil {
+		return nil
+	}
+	prefix, err := cacheKeyPrefix(tool, arguments)
+	if err != nil {
+		return err
+	}
+	description.Cache.store().DeletePrefix(prefix)
+	return nil
+}
+
+// LRUCache is an in-memory CacheStore that evicts the least recently used entries beyond its capacity
+type LRUCache struct {
+	capacity int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type lruEntry struct {
+	key     string
+	value   any
+	expires time.Time
+}
+
+// NewLRUCache creates a store holding up to capacity entries, DefaultCacheEntries when not positive
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity <= 0 {
+		capacity = DefaultCacheEntries
+	}
+	return &LRUCache{capacity: capacity, now: time.Now, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+// Get returns the value stored under key unless it expired
+func (c *LRUCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set stores value under key for ttl, evicting the least recently used entry when full
+func (c *LRUCache) Set(key string, value any, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// DeletePrefix removes every entry whose key starts with prefix
+func (c *LRUCache) DeletePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(element)
+		}
+	}
+}
+
+// Len returns the number of stored entries, including expired ones not yet evicted
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRUCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry).key)
+}
@@ -0,0 +1,217 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cachedCounterTool(calls *int, cache *ToolCache) ToolDescription {
+	return ToolDescription{
+		Name:  "counter",
+		Cache: cache,
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			*calls++
+			if params["partial"] == true {
+				SkipCache(r.Context())
+			}
+			return map[string]any{"calls": *calls}, nil
+		},
+	}
+}
+
+func callCached(t *testing.T, server *Server, r *http.Request, arguments map[string]any) int {
+	t.Helper()
+	result, _, err := server.callTool(r, "counter", arguments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.(map[string]any)["calls"].(int)
+}
+
+func TestToolCacheServesRepeatedCalls(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	metrics := server.EnableMetrics("")
+	calls := 0
+	server.RegisterTool(cachedCounterTool(&calls, &ToolCache{TTL: time.Minute}))
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	first := callCached(t, server, r, map[string]any{"zone": "UTC", "options": map[string]any{"a": 1, "b": 2}})
+	// Key order does not matter, the arguments are canonicalized
+	second := callCached(t, server, r, map[string]any{"options": map[string]any{"b": 2, "a": 1}, "zone": "UTC"})
+	if first != 1 || second != 1 {
+		t.Errorf("expected the second call to hit the cache, got %d and %d", first, second)
+	}
+	if third := callCached(t, server, r, map[string]any{"zone": "Europe/Madrid"}); third != 2 {
+		t.Errorf("expected other arguments to miss the cache, got %d", third)
+	}
+
+	var page strings.Builder
+	metrics.WriteTo(&page)
+	if !strings.Contains(page.String(), `mcp_tool_cache_hits_total{tool="counter"} 1`) || !strings.Contains(page.String(), `mcp_tool_cache_misses_total{tool="counter"} 2`) {
+		t.Errorf("unexpected cache metrics:\n%s", page.String())
+	}
+}
+
+func TestToolCacheBypassAndInvalidation(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	calls := 0
+	server.RegisterTool(cachedCounterTool(&calls, &ToolCache{TTL: time.Minute}))
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	arguments := map[string]any{"zone": "UTC"}
+
+	callCached(t, server, r, arguments)
+	fresh := r.Clone(context.Background())
+	fresh.Header.Set("Cache-Control", "no-cache")
+	if got := callCached(t, server, fresh, arguments); got != 2 {
+		t.Errorf("expected no-cache to run the handler, got %d", got)
+	}
+	if got := callCached(t, server, r, arguments); got != 2 {
+		t.Errorf("expected the fresh result to refresh the cache, got %d", got)
+	}
+
+	if err := server.InvalidateCache("counter", arguments); err != nil {
+		t.Fatal(err)
+	}
+	if got := callCached(t, server, r, arguments); got != 3 {
+		t.Errorf("expected invalidated arguments to run the handler, got %d", got)
+	}
+	if err := server.InvalidateCache("counter", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := callCached(t, server, r, arguments); got != 4 {
+		t.Errorf("expected invalidating the tool to drop every entry, got %d", got)
+	}
+
+	partial := map[string]any{"partial": true}
+	callCached(t, server, r, partial)
+	if got := callCached(t, server, r, partial); got != 6 {
+		t.Errorf("expected SkipCache to keep results out of the cache, got %d", got)
+	}
+}
+
+func TestToolCachePerCaller(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	calls := 0
+	server.RegisterTool(cachedCounterTool(&calls, &ToolCache{TTL: time.Minute, PerCaller: true, Identity: func(r *http.Request) string {
+		return r.Header.Get("X-User")
+	}}))
+
+	alice := httptest.NewRequest(http.MethodPost, "/", nil)
+	alice.Header.Set("X-User", "alice")
+	bob := httptest.NewRequest(http.MethodPost, "/", nil)
+	bob.Header.Set("X-User", "bob")
+
+	callCached(t, server, alice, nil)
+	if got := callCached(t, server, bob, nil); got != 2 {
+		t.Errorf("expected callers not to share entries, got %d", got)
+	}
+	if got := callCached(t, server, alice, nil); got != 1 {
+		t.Errorf("expected alice's entry to be cached, got %d", got)
+	}
+}
+
+func TestToolCacheRequiresTTL(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	calls := 0
+	if err := server.RegisterTool(cachedCounterTool(&calls, &ToolCache{})); err == nil {
+		t.Error("expected a cache without TTL to be rejected")
+	}
+	if err := server.RegisterTool(cachedCounterTool(&calls, &ToolCache{TTL: time.Minute, PerCaller: true})); err == nil {
+		t.Error("expected a per caller cache without Identity to be rejected")
+	}
+}
+
+func TestReplacedToolDoesNotRefillCache(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	cache := &ToolCache{TTL: time.Minute}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server.RegisterTool(ToolDescription{
+		Name:  "version",
+		Cache: cache,
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			close(started)
+			<-release
+			return "old", nil
+		},
+	})
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.callTool(r, "version", nil)
+	}()
+	<-started
+	if err := server.ReplaceTool(ToolDescription{Name: "version", Cache: cache, Handler: namedTool("version", "new").Handler}); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-done
+
+	if result, _, err := server.callTool(r, "version", nil); err != nil || result != "new" {
+		t.Errorf("expected the call still running on the old definition not to be cached, got %v %v", result, err)
+	}
+}
+
+func TestLRUCacheEvictsAndExpires(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewLRUCache(2)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("a", 1, time.Minute)
+	cache.Set("b", 2, time.Minute)
+	cache.Get("a")
+	cache.Set("c", 3, time.Minute)
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	if value, ok := cache.Get("a"); !ok || value != 1 {
+		t.Errorf("expected a to stay cached, got %v", value)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := cache.Get("c"); ok || cache.Len() != 1 {
+		t.Errorf("expected expired entries to be dropped, %d left", cache.Len())
+	}
+}
+
+func TestOverrideToolHandlerKeepsCache(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	calls := 0
+	server.RegisterTool(cachedCounterTool(&calls, &ToolCache{TTL: time.Minute}))
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	callCached(t, server, r, nil)
+
+	restore, err := server.overrideToolHandler("counter", func(r *http.Request, params map[string]any) (any, error) {
+		return map[string]any{"calls": 100}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := callCached(t, server, r, nil); got != 100 {
+		t.Errorf("expected the override to run, got %d", got)
+	}
+
+	if err := restore(); err != nil {
+		t.Fatal(err)
+	}
+	if got := callCached(t, server, r, nil); got != 1 || calls != 1 {
+		t.Errorf("expected the original cached result after restoring, got %d after %d calls", got, calls)
+	}
+	if _, err := server.overrideToolHandler("missing", nil); !errors.Is(err, ErrToolNotFound) {
+		t.Errorf("expected ErrToolNotFound, got %v", err)
+	}
+
+	// Restoring a tool unregistered in the meantime fails instead of registering it again
+	restore, _ = server.overrideToolHandler("counter", cachedCounterTool(&calls, nil).Handler)
+	server.UnregisterTool("counter")
+	if err := restore(); !errors.Is(err, ErrToolNotFound) || server.FindTool("counter") != nil {
+		t.Errorf("expected restore to fail with ErrToolNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Package client implements a Model Context Protocol (MCP) client for the Streamable HTTP transport
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Backend creates a server exposing the tools, prompts, resources and resource templates the server c is
+// connected to lists now, forwarding every call through c. Mount it on a gateway with mcp.Server.Mount to
+// compose servers deployed separately. The remote server applies its own limits, so the backend sets none.
+func Backend(ctx context.Context, c *Client) (*mcp.Server, error) {
+	info := c.ServerInfo()
+	if info == nil {
+		return nil, ErrNotInitialized
+	}
+	name, _ := info.ServerInfo["name"].(string)
+	version, _ := info.ServerInfo["version"].(string)
+	description, _ := info.ServerInfo["description"].(string)
+	backend := mcp.NewServer(name, version, description)
+
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the tools of %s: %w", name, err)
+	}
+	for _, tool := range tools {
+		remote, err := remoteTool(c, tool)
+		if err != nil {
+			return nil, err
+		}
+		if err := backend.RegisterTool(remote); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := info.Capabilities["prompts"]; ok {
+		prompts, err := c.ListPrompts(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the prompts of %s: %w", name, err)
+		}
+		for _, prompt := range prompts {
+			if err := backend.RegisterPrompt(remotePrompt(c, prompt)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if _, ok := info.Capabilities["resources"]; ok {
+		if err := registerRemoteResources(ctx, c, backend); err != nil {
+			return nil, fmt.Errorf("failed to list the resources of %s: %w", name, err)
+		}
+	}
+	return backend, nil
+}
+
+// remoteTool describes tool with OpenAPI schemas, keywords OpenAPI lacks are dropped
+func remoteTool(c *Client, tool Tool) (mcp.ToolDescription, error) {
+	description := mcp.ToolDescription{
+		Name:        tool.Name,
+		Description: tool.Description,
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			result, err := c.CallTool(r.Context(), tool.Name, params)
+			if err != nil {
+				return nil, err
+			}
+			// The CallToolResult is passed on as the server returned it
+			var raw map[string]any
+			if err := json.Unmarshal(result.Raw, &raw); err != nil {
+				return nil, fmt.Errorf("invalid result of remote tool %s: %w", tool.Name, err)
+			}
+			return raw, nil
+		},
+	}
+	var err error
+	if description.InputSchema, err = decodeSchema(tool.InputSchema); err != nil {
+		return description, fmt.Errorf("invalid input schema of remote tool %s: %w", tool.Name, err)
+	}
+	if description.OutputSchema, err = decodeSchema(tool.OutputSchema); err != nil {
+		return description, fmt.Errorf("invalid output schema of remote tool %s: %w", tool.Name, err)
+	}
+	return description, nil
+}
+
+func decodeSchema(schema map[string]any) (*openapi3.Schema, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	payload, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	decoded := &openapi3.Schema{}
+	if err := json.Unmarshal(payload, decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+func remotePrompt(c *Client, prompt mcp.Prompt) mcp.Prompt {
+	prompt.Handler = func(r *http.Request, arguments map[string]string) (*mcp.PromptResult, error) {
+		var result mcp.PromptResult
+		if err := c.Call(r.Context(), "prompts/get", map[string]any{"name": prompt.Name, "arguments": arguments}, &result); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	}
+	return prompt
+}
+
+func registerRemoteResources(ctx context.Context, c *Client, backend *mcp.Server) error {
+	resources, err := c.ListResources(ctx)
+	if err != nil {
+		return err
+	}
+	for _, resource := range resources {
+		resource.Handler = func(r *http.Request, uri string) ([]mcp.ResourceContents, error) {
+			return c.ReadResource(r.Context(), uri)
+		}
+		if err := backend.RegisterResource(resource); err != nil {
+			return err
+		}
+	}
+
+	templates, err := c.ListResourceTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	for _, template := range templates {
+		template.Handler = func(r *http.Request, uri string, variables map[string]string) ([]mcp.ResourceContents, error) {
+			return c.ReadResource(r.Context(), uri)
+		}
+		if err := backend.RegisterResourceTemplate(template); err != nil {
+			return err
+		}
+	}
+	return nil
+}
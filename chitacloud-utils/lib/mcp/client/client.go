@@ -0,0 +1,376 @@
+// Package client implements a Model Context Protocol (MCP) client for the Streamable HTTP transport
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+)
+
+// DefaultProtocolVersion is the protocol version requested during initialize
+const DefaultProtocolVersion = "2025-06-18"
+
+// ErrNotInitialized is returned when calling a server before Initialize
+var ErrNotInitialized = errors.New("client is not initialized")
+
+// ErrSessionExpired is returned when the server answers 404 to a request of a session it no longer knows
+var ErrSessionExpired = errors.New("session expired")
+
+// HTTPError is returned when the server answers with an HTTP error and no JSON-RPC error
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d: %s", e.StatusCode, e.Body)
+}
+
+// InitializeResult is the server's answer to initialize
+type InitializeResult struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ServerInfo      map[string]any `json:"serverInfo"`
+	Instructions    string         `json:"instructions,omitempty"`
+}
+
+// Progress is the payload of notifications/progress
+type Progress struct {
+	ProgressToken any     `json:"progressToken"`
+	Progress      float64 `json:"progress"`
+	Total         float64 `json:"total,omitempty"`
+	Message       string  `json:"message,omitempty"`
+}
+
+// Client talks to an MCP server over Streamable HTTP, it is safe for concurrent use once initialized
+type Client struct {
+	Endpoint string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// Header holds extra headers sent with every request, e.g. Authorization
+	Header          http.Header
+	ClientInfo      map[string]any
+	Capabilities    map[string]any
+	ProtocolVersion string
+
+	// OnNotification receives the notifications sent by the server
+	OnNotification func(method string, params json.RawMessage)
+	// OnRequest answers server-to-client requests such as sampling/createMessage or elicitation/create
+	OnRequest func(ctx context.Context, method string, params json.RawMessage) (any, error)
+
+	mu         sync.Mutex
+	nextID     int
+	sessionID  string
+	initialize *InitializeResult
+	// renewMu serializes the initialize calls replacing an expired session
+	renewMu sync.Mutex
+}
+
+// New creates a client for the MCP server at endpoint
+func New(endpoint string) *Client {
+	return &Client{
+		Endpoint:        endpoint,
+		ClientInfo:      map[string]any{"name": "chitacloud-mcp-client", "version": "1.0.0"},
+		Capabilities:    map[string]any{},
+		ProtocolVersion: DefaultProtocolVersion,
+	}
+}
+
+// SessionID returns the session assigned by the server during initialize
+func (c *Client) SessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionID
+}
+
+// ServerInfo returns the result of initialize, nil before Initialize
+func (c *Client) ServerInfo() *InitializeResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.initialize
+}
+
+// Initialize negotiates the protocol version and capabilities, then sends notifications/initialized
+func (c *Client) Initialize(ctx context.Context) (*InitializeResult, error) {
+	var result InitializeResult
+	params := map[string]any{
+		"protocolVersion": c.ProtocolVersion,
+		"capabilities":    c.Capabilities,
+		"clientInfo":      c.ClientInfo,
+	}
+	if err := c.call(ctx, "initialize", params, &result, nil); err != nil {
+		return nil, fmt.Errorf("initialize failed: %w", err)
+	}
+
+	c.mu.Lock()
+	c.initialize = &result
+	c.mu.Unlock()
+
+	if err := c.Notify(ctx, "notifications/initialized", nil); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Call sends a request and decodes its result into result, which may be nil. When the server no longer knows
+// the session, the client initializes a new one and sends the request once more.
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	return c.callSession(ctx, method, params, result, nil)
+}
+
+func (c *Client) callSession(ctx context.Context, method string, params any, result any, onProgress func(Progress)) error {
+	if c.ServerInfo() == nil {
+		return ErrNotInitialized
+	}
+	err := c.call(ctx, method, params, result, onProgress)
+	var expired *sessionExpiredError
+	if !errors.As(err, &expired) {
+		return err
+	}
+	if err := c.renewSession(ctx, expired.sessionID); err != nil {
+		return err
+	}
+	return c.call(ctx, method, params, result, onProgress)
+}
+
+// renewSession initializes a new session in place of expired, unless a concurrent call already did
+func (c *Client) renewSession(ctx context.Context, expired string) error {
+	c.renewMu.Lock()
+	defer c.renewMu.Unlock()
+
+	c.mu.Lock()
+	current := c.sessionID
+	if current == expired {
+		c.sessionID = ""
+	}
+	c.mu.Unlock()
+	if current != expired {
+		return nil
+	}
+	_, err := c.Initialize(ctx)
+	return err
+}
+
+// sessionExpiredError carries the session id the server answered 404 to
+type sessionExpiredError struct {
+	sessionID string
+}
+
+func (e *sessionExpiredError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrSessionExpired, e.sessionID)
+}
+
+func (e *sessionExpiredError) Unwrap() error {
+	return ErrSessionExpired
+}
+
+// Notify sends a notification, which the server acknowledges without a result
+func (c *Client) Notify(ctx context.Context, method string, params any) error {
+	notification := map[string]any{"jsonrpc": "2.0", "method": method}
+	if params != nil {
+		notification["params"] = params
+	}
+	resp, err := c.post(ctx, notification)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return httpError(resp)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Listen opens the GET stream of the session and dispatches the server notifications and requests
+// it carries until ctx is done or the server closes the stream
+func (c *Client) Listen(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Endpoint, nil)
+	if err != nil {
+		return err
+	}
+	c.setHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to open stream: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return httpError(resp)
+	}
+
+	err = ReadMessages(resp.Header.Get("Content-Type"), resp.Body, func(message Message) error {
+		c.dispatch(ctx, message, nil)
+		return nil
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// call sends a request and waits for its response, dispatching the messages received meanwhile
+func (c *Client) call(ctx context.Context, method string, params any, result any, onProgress func(Progress)) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.mu.Unlock()
+
+	request := map[string]any{"jsonrpc": "2.0", "id": id, "method": method}
+	if params != nil {
+		request["params"] = params
+	}
+	resp, err := c.post(ctx, request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// The Streamable HTTP transport answers 404 once the server has forgotten the session
+	if sessionID := resp.Request.Header.Get(mcp.SessionHeader); sessionID != "" && resp.StatusCode == http.StatusNotFound {
+		return &sessionExpiredError{sessionID: sessionID}
+	}
+
+	var response *Message
+	err = ReadMessages(resp.Header.Get("Content-Type"), resp.Body, func(message Message) error {
+		if message.IsResponse() {
+			if string(message.ID) == strconv.Itoa(id) {
+				response = &message
+				return errStopReading
+			}
+			return nil
+		}
+		c.dispatch(ctx, message, onProgress)
+		return nil
+	})
+	if err != nil && resp.StatusCode < http.StatusBadRequest {
+		return err
+	}
+
+	if response == nil {
+		if resp.StatusCode >= http.StatusBadRequest {
+			return httpError(resp)
+		}
+		return fmt.Errorf("%s: no response received", method)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if result == nil || len(response.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("invalid %s result: %w", method, err)
+	}
+	return nil
+}
+
+// dispatch handles a server notification or request received while waiting for a response
+func (c *Client) dispatch(ctx context.Context, message Message, onProgress func(Progress)) {
+	switch {
+	case message.IsRequest():
+		// The server usually blocks until it gets the answer, so it is sent without waiting
+		go c.answer(ctx, message)
+	case message.IsNotification():
+		if message.Method == "notifications/progress" && onProgress != nil {
+			var progress Progress
+			if err := json.Unmarshal(message.Params, &progress); err == nil {
+				onProgress(progress)
+			}
+		}
+		if c.OnNotification != nil {
+			c.OnNotification(message.Method, message.Params)
+		}
+	}
+}
+
+// answer runs OnRequest for a server request and posts its response
+func (c *Client) answer(ctx context.Context, request Message) {
+	reply := map[string]any{"jsonrpc": "2.0", "id": request.ID}
+	if c.OnRequest == nil {
+		reply["error"] = mcp.JsonRPCError{Code: mcp.ErrMethodNotFound, Message: "client does not handle " + request.Method}
+	} else if result, err := c.OnRequest(ctx, request.Method, request.Params); err != nil {
+		var rpcErr *mcp.JsonRPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &mcp.JsonRPCError{Code: mcp.ErrInternal, Message: err.Error()}
+		}
+		reply["error"] = rpcErr
+	} else if result == nil {
+		reply["result"] = map[string]any{}
+	} else {
+		reply["result"] = result
+	}
+
+	resp, err := c.post(ctx, reply)
+	if err != nil {
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// post sends a JSON-RPC message and keeps the session id assigned by the server
+func (c *Client) post(ctx context.Context, message any) (*http.Response, error) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if sessionID := resp.Header.Get(mcp.SessionHeader); sessionID != "" {
+		c.mu.Lock()
+		c.sessionID = sessionID
+		c.mu.Unlock()
+	}
+	return resp, nil
+}
+
+func (c *Client) setHeaders(req *http.Request) {
+	for name, values := range c.Header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	c.mu.Lock()
+	sessionID := c.sessionID
+	initialized := c.initialize
+	c.mu.Unlock()
+	if sessionID != "" {
+		req.Header.Set(mcp.SessionHeader, sessionID)
+	}
+	if initialized != nil {
+		req.Header.Set(mcp.ProtocolVersionHeader, initialized.ProtocolVersion)
+	}
+	mcp.InjectTraceContext(req.Context(), req.Header)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func httpError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+}
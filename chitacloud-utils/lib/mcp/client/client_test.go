@@ -0,0 +1,336 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+)
+
+func newTestServer(t *testing.T, configure func(*mcp.Server)) *Client {
+	t.Helper()
+	server := mcp.NewServer("test", "1.0", "test server")
+	server.RegisterTool(mcp.ToolDescription{
+		Name: "echo",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return map[string]any{"echo": params["text"]}, nil
+		},
+	})
+	server.RegisterTool(mcp.ToolDescription{
+		Name: "letters",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return []string{"a", "b", "c"}, nil
+		},
+	})
+	server.RegisterTool(mcp.ToolDescription{
+		Name: "fail",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return nil, &mcp.JsonRPCError{Code: mcp.ErrInvalidParams, Message: "bad input"}
+		},
+	})
+	if configure != nil {
+		configure(server)
+	}
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	c := New(httpServer.URL)
+	if _, err := c.Initialize(context.Background()); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+	return c
+}
+
+func TestInitializeAndListTools(t *testing.T) {
+	c := newTestServer(t, nil)
+	if c.SessionID() == "" {
+		t.Error("expected a session id after initialize")
+	}
+	if c.ServerInfo().ServerInfo["name"] != "test" {
+		t.Errorf("unexpected server info %v", c.ServerInfo())
+	}
+
+	tools, err := c.ListTools(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 3 || tools[0].Name != "echo" {
+		t.Errorf("unexpected tools %v", tools)
+	}
+	if err := c.Ping(context.Background()); err != nil {
+		t.Errorf("ping failed: %v", err)
+	}
+}
+
+func TestCallTool(t *testing.T) {
+	c := newTestServer(t, nil)
+
+	result, err := c.CallTool(context.Background(), "echo", map[string]any{"text": "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Echo string `json:"echo"`
+	}
+	if err := result.Decode(&decoded); err != nil || decoded.Echo != "hi" {
+		t.Errorf("unexpected result %s (%v)", result.Raw, err)
+	}
+
+	_, err = c.CallTool(context.Background(), "fail", nil)
+	var rpcErr *mcp.JsonRPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != mcp.ErrInvalidParams {
+		t.Errorf("expected a JSON-RPC error, got %v", err)
+	}
+}
+
+func TestReinitializesExpiredSession(t *testing.T) {
+	var server *mcp.Server
+	c := newTestServer(t, func(s *mcp.Server) { server = s })
+	expired := c.SessionID()
+	server.CloseSession(expired)
+
+	if _, err := c.CallTool(context.Background(), "echo", map[string]any{"text": "hi"}); err != nil {
+		t.Fatalf("expected the call to succeed on a new session, got %v", err)
+	}
+	if c.SessionID() == expired || server.Session(c.SessionID()) == nil {
+		t.Errorf("expected a new session in place of %s, got %s", expired, c.SessionID())
+	}
+
+	// A server that keeps forgetting the session is not retried more than once
+	server.CloseSession(c.SessionID())
+	calls := 0
+	c.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		if body, err := r.GetBody(); err == nil {
+			if payload, _ := io.ReadAll(body); strings.Contains(string(payload), `"method":"ping"`) {
+				server.CloseSession(r.Header.Get(mcp.SessionHeader))
+			}
+		}
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	if err := c.Ping(context.Background()); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("expected ErrSessionExpired after one retry, got %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("expected the request, initialize, initialized and the retry, got %d requests", calls)
+	}
+}
+
+func TestBackendMountsRemoteServer(t *testing.T) {
+	remote := newTestServer(t, func(server *mcp.Server) {
+		server.RegisterPrompt(mcp.Prompt{
+			Name:      "greet",
+			Arguments: []mcp.PromptArgument{{Name: "name", Required: true}},
+			Handler: func(r *http.Request, arguments map[string]string) (*mcp.PromptResult, error) {
+				return &mcp.PromptResult{Messages: []mcp.SamplingMessage{mcp.TextMessage("user", "Hello "+arguments["name"])}}, nil
+			},
+		})
+		server.RegisterResource(mcp.Resource{URI: "test://status", Name: "status", Handler: func(r *http.Request, uri string) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{{Text: "ok"}}, nil
+		}})
+	})
+	backend, err := Backend(context.Background(), remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway := mcp.NewServer("gateway", "1.0", "gateway")
+	if err := gateway.Mount("remote", backend); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(gateway)
+	t.Cleanup(httpServer.Close)
+	c := New(httpServer.URL)
+	if _, err := c.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tools, err := c.ListTools(context.Background())
+	if err != nil || len(tools) != 3 || tools[0].Name != "remote.echo" || tools[0].InputSchema["type"] != "object" {
+		t.Fatalf("expected the remote tools under the prefix, got %v %v", tools, err)
+	}
+	result, err := c.CallTool(context.Background(), "remote.echo", map[string]any{"text": "hi"})
+	var decoded struct {
+		Echo string `json:"echo"`
+	}
+	if err != nil || result.Decode(&decoded) != nil || decoded.Echo != "hi" {
+		t.Errorf("expected the remote result, got %v %v", result, err)
+	}
+	var rpcErr *mcp.JsonRPCError
+	if _, err := c.CallTool(context.Background(), "remote.fail", nil); !errors.As(err, &rpcErr) || rpcErr.Code != mcp.ErrInvalidParams {
+		t.Errorf("expected the remote error, got %v", err)
+	}
+
+	prompt, err := c.GetPrompt(context.Background(), "remote.greet", map[string]string{"name": "Ada"})
+	if err != nil || prompt.Messages[0].Content.Text != "Hello Ada" {
+		t.Errorf("expected the remote prompt, got %v %v", prompt, err)
+	}
+	contents, err := c.ReadResource(context.Background(), "test://status")
+	if err != nil || contents[0].Text != "ok" {
+		t.Errorf("expected the remote resource, got %v %v", contents, err)
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestCallToolReportsProgress(t *testing.T) {
+	c := newTestServer(t, nil)
+
+	var progress []Progress
+	result, err := c.CallTool(context.Background(), "letters", nil, WithProgress(func(p Progress) {
+		progress = append(progress, p)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) != 3 || progress[2].Progress != 3 || progress[2].Total != 3 {
+		t.Errorf("unexpected progress %v", progress)
+	}
+	if progress[0].ProgressToken == nil {
+		t.Error("expected the progress token to be echoed")
+	}
+
+	var decoded struct {
+		Items []string `json:"items"`
+	}
+	if err := result.Decode(&decoded); err != nil || len(decoded.Items) != 3 {
+		t.Errorf("unexpected result %s (%v)", result.Raw, err)
+	}
+	if !strings.Contains(result.Text(), `"items"`) {
+		t.Errorf("expected a text content block, got %v", result.Content)
+	}
+}
+
+func TestAnswersServerRequests(t *testing.T) {
+	server := mcp.NewServer("test", "1.0", "test server")
+	server.SetStreaming(true)
+	server.RegisterTool(mcp.ToolDescription{
+		Name: "summarize",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			result, err := mcp.CreateMessage(r.Context(), mcp.SamplingRequest{
+				Messages:  []mcp.SamplingMessage{mcp.TextMessage("user", "summarize")},
+				MaxTokens: 10,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"summary": result.Content.Text}, nil
+		},
+	})
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	c := New(httpServer.URL)
+	c.Capabilities = map[string]any{"sampling": map[string]any{}}
+	c.OnRequest = func(ctx context.Context, method string, params json.RawMessage) (any, error) {
+		if method != "sampling/createMessage" {
+			return nil, errors.New("unexpected method " + method)
+		}
+		return mcp.SamplingResult{Role: "assistant", Content: mcp.SamplingContent{Type: "text", Text: "short"}, Model: "test"}, nil
+	}
+	if _, err := c.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.CallTool(context.Background(), "summarize", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]string
+	if err := result.Decode(&decoded); err != nil || decoded["summary"] != "short" {
+		t.Errorf("unexpected result %s (%v)", result.Raw, err)
+	}
+}
+
+func TestResources(t *testing.T) {
+	c := newTestServer(t, func(server *mcp.Server) {
+		server.RegisterResource(mcp.Resource{
+			URI:      "memo://greeting",
+			Name:     "greeting",
+			MimeType: "text/plain",
+			Handler: func(r *http.Request, uri string) ([]mcp.ResourceContents, error) {
+				return []mcp.ResourceContents{{Text: "hello"}}, nil
+			},
+		})
+	})
+
+	resources, err := c.ListResources(context.Background())
+	if err != nil || len(resources) != 1 || resources[0].URI != "memo://greeting" {
+		t.Fatalf("unexpected resources %v (%v)", resources, err)
+	}
+	contents, err := c.ReadResource(context.Background(), "memo://greeting")
+	if err != nil || len(contents) != 1 || contents[0].Text != "hello" {
+		t.Errorf("unexpected contents %v (%v)", contents, err)
+	}
+}
+
+func TestListenReceivesNotifications(t *testing.T) {
+	server := mcp.NewServer("test", "1.0", "test server")
+	server.SetStreaming(true)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	c := New(httpServer.URL)
+	received := make(chan string, 1)
+	c.OnNotification = func(method string, params json.RawMessage) {
+		received <- method
+	}
+	if _, err := c.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Listen(ctx)
+
+	// The stream is attached asynchronously
+	deadline := time.Now().Add(time.Second)
+	for !server.Session(c.SessionID()).HasOpenStream() {
+		if time.Now().After(deadline) {
+			t.Fatal("stream was not opened")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	server.RegisterTool(mcp.ToolDescription{Name: "late", Handler: func(r *http.Request, params map[string]any) (any, error) { return nil, nil }})
+
+	select {
+	case method := <-received:
+		if method != "notifications/tools/list_changed" {
+			t.Errorf("unexpected notification %s", method)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notification received")
+	}
+}
+
+func TestParseMessages(t *testing.T) {
+	messages, err := ParseMessages("text/event-stream", strings.NewReader(": keepalive\n\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{\"progress\":1}}\n\ndata: {\"jsonrpc\":\"2.0\",\"id\":1,\n\ndata: \"result\":{}}\n\n"))
+	if err == nil {
+		t.Fatalf("expected an error for a broken event, got %v", messages)
+	}
+
+	messages, err = ParseMessages("", strings.NewReader("data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\ndata: {\"jsonrpc\":\"2.0\",\ndata: \"id\":1,\"result\":{}}\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || !messages[0].IsNotification() || !messages[1].IsResponse() {
+		t.Errorf("unexpected messages %+v", messages)
+	}
+
+	messages, err = ParseMessages("application/json", strings.NewReader(`{"jsonrpc":"2.0","id":3,"error":{"code":-32601,"message":"nope"}}`))
+	if err != nil || len(messages) != 1 || messages[0].Error.Code != mcp.ErrMethodNotFound {
+		t.Errorf("unexpected messages %+v (%v)", messages, err)
+	}
+}
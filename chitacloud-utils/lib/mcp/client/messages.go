@@ -0,0 +1,130 @@
+// Package client implements a Model Context Protocol (MCP) client for the Streamable HTTP transport
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+)
+
+// Message is a JSON-RPC message received from or sent to a server
+type Message struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Params  json.RawMessage   `json:"params,omitempty"`
+	Result  json.RawMessage   `json:"result,omitempty"`
+	Error   *mcp.JsonRPCError `json:"error,omitempty"`
+}
+
+// IsRequest reports whether the message is a server-to-client request
+func (m Message) IsRequest() bool {
+	return m.Method != "" && len(m.ID) > 0
+}
+
+// IsNotification reports whether the message is a notification
+func (m Message) IsNotification() bool {
+	return m.Method != "" && len(m.ID) == 0
+}
+
+// IsResponse reports whether the message answers a request
+func (m Message) IsResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+// errStopReading ends ReadMessages without reporting an error
+var errStopReading = errors.New("stop reading")
+
+// ParseMessages reads every JSON-RPC message of a response body, which is either a single JSON
+// message or an SSE stream when contentType is text/event-stream. An empty contentType is detected from the body.
+func ParseMessages(contentType string, body io.Reader) ([]Message, error) {
+	var messages []Message
+	err := ReadMessages(contentType, body, func(message Message) error {
+		messages = append(messages, message)
+		return nil
+	})
+	return messages, err
+}
+
+// ReadMessages calls handle for each message of body as soon as it is received, which lets callers
+// answer server requests while the response is still streaming. Reading stops at the first handle error.
+func ReadMessages(contentType string, body io.Reader, handle func(Message) error) error {
+	reader := bufio.NewReader(body)
+	if contentType == "" {
+		peeked, _ := reader.Peek(1)
+		if len(peeked) == 1 && peeked[0] != '{' {
+			contentType = "text/event-stream"
+		}
+	}
+
+	var err error
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		err = readEvents(reader, handle)
+	} else {
+		err = readJSON(reader, handle)
+	}
+	if errors.Is(err, errStopReading) {
+		return nil
+	}
+	return err
+}
+
+func readJSON(reader io.Reader, handle func(Message) error) error {
+	payload, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if strings.TrimSpace(string(payload)) == "" {
+		return nil
+	}
+	var message Message
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return fmt.Errorf("invalid JSON-RPC message: %w", err)
+	}
+	return handle(message)
+}
+
+// readEvents parses SSE events, joining multi-line data fields and skipping comments
+func readEvents(reader *bufio.Reader, handle func(Message) error) error {
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		payload := strings.Join(data, "\n")
+		data = data[:0]
+		var message Message
+		if err := json.Unmarshal([]byte(payload), &message); err != nil {
+			return fmt.Errorf("invalid JSON-RPC event %q: %w", payload, err)
+		}
+		return handle(message)
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			line = strings.TrimRight(line, "\r\n")
+			switch {
+			case line == "":
+				if dispatchErr := dispatch(); dispatchErr != nil {
+					return dispatchErr
+				}
+			case strings.HasPrefix(line, ":"):
+				// Comment, used for heartbeats
+			case strings.HasPrefix(line, "data:"):
+				data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			}
+		}
+		if err == io.EOF {
+			return dispatch()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read event stream: %w", err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Package client implements a Model Context Protocol (MCP) client for the Streamable HTTP transport
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+)
+
+// ContentBlock is a text, image, audio or embedded resource content item
+type ContentBlock struct {
+	Type     string                `json:"type"`
+	Text     string                `json:"text,omitempty"`
+	Data     string                `json:"data,omitempty"`
+	MimeType string                `json:"mimeType,omitempty"`
+	Resource *mcp.ResourceContents `json:"resource,omitempty"`
+}
+
+// Tool is a tool listed by tools/list, its schemas are JSON Schema documents
+type Tool struct {
+	Name         string         `json:"name"`
+	Description  string         `json:"description,omitempty"`
+	InputSchema  map[string]any `json:"inputSchema"`
+	OutputSchema map[string]any `json:"outputSchema,omitempty"`
+}
+
+// ToolResult is the result of tools/call
+type ToolResult struct {
+	Content           []ContentBlock  `json:"content,omitempty"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+	// Raw is the result exactly as returned by the server
+	Raw json.RawMessage `json:"-"`
+}
+
+// Decode decodes the structured content of the result into v, or the raw result when the
+// server returned it unwrapped
+func (r *ToolResult) Decode(v any) error {
+	payload := r.StructuredContent
+	if len(payload) == 0 {
+		payload = r.Raw
+	}
+	return json.Unmarshal(payload, v)
+}
+
+// Text joins the text content blocks of the result
+func (r *ToolResult) Text() string {
+	var text string
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			text += block.Text
+		}
+	}
+	return text
+}
+
+// CallOption customizes a tools/call request
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	onProgress func(Progress)
+	meta       map[string]any
+}
+
+// WithProgress requests progress notifications and passes them to fn as they arrive
+func WithProgress(fn func(Progress)) CallOption {
+	return func(o *callOptions) {
+		o.onProgress = fn
+	}
+}
+
+// WithMeta adds a field to the _meta of the request
+func WithMeta(key string, value any) CallOption {
+	return func(o *callOptions) {
+		if o.meta == nil {
+			o.meta = map[string]any{}
+		}
+		o.meta[key] = value
+	}
+}
+
+// Ping checks that the server is alive
+func (c *Client) Ping(ctx context.Context) error {
+	return c.Call(ctx, "ping", nil, nil)
+}
+
+// ListTools returns the tools exposed by the server
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var result struct {
+		Tools []Tool `json:"tools"`
+	}
+	if err := c.Call(ctx, "tools/list", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Tools, nil
+}
+
+// CallTool runs a tool, tool failures are returned as *mcp.JsonRPCError
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]any, opts ...CallOption) (*ToolResult, error) {
+	if c.ServerInfo() == nil {
+		return nil, ErrNotInitialized
+	}
+
+	var options callOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	params := map[string]any{"name": name, "arguments": arguments}
+	if options.onProgress != nil {
+		WithMeta("progressToken", fmt.Sprintf("%s-%s", name, c.SessionID()))(&options)
+	}
+	if options.meta != nil {
+		params["_meta"] = options.meta
+	}
+
+	var raw json.RawMessage
+	if err := c.callSession(ctx, "tools/call", params, &raw, options.onProgress); err != nil {
+		return nil, err
+	}
+	result := &ToolResult{Raw: raw}
+	// Results that are not objects are kept in Raw only
+	_ = json.Unmarshal(raw, result)
+	return result, nil
+}
+
+// ListResources returns the resources exposed by the server
+func (c *Client) ListResources(ctx context.Context) ([]mcp.Resource, error) {
+	var result struct {
+		Resources []mcp.Resource `json:"resources"`
+	}
+	if err := c.Call(ctx, "resources/list", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Resources, nil
+}
+
+// ListResourceTemplates returns the resource templates exposed by the server
+func (c *Client) ListResourceTemplates(ctx context.Context) ([]mcp.ResourceTemplate, error) {
+	var result struct {
+		ResourceTemplates []mcp.ResourceTemplate `json:"resourceTemplates"`
+	}
+	if err := c.Call(ctx, "resources/templates/list", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.ResourceTemplates, nil
+}
+
+// ReadResource returns the contents of the resource at uri
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	var result struct {
+		Contents []mcp.ResourceContents `json:"contents"`
+	}
+	if err := c.Call(ctx, "resources/read", map[string]any{"uri": uri}, &result); err != nil {
+		return nil, err
+	}
+	return result.Contents, nil
+}
+
+// Subscribe asks for notifications/resources/updated when the resource at uri changes
+func (c *Client) Subscribe(ctx context.Context, uri string) error {
+	return c.Call(ctx, "resources/subscribe", map[string]any{"uri": uri}, nil)
+}
+
+// Unsubscribe stops the updates of the resource at uri
+func (c *Client) Unsubscribe(ctx context.Context, uri string) error {
+	return c.Call(ctx, "resources/unsubscribe", map[string]any{"uri": uri}, nil)
+}
+
+// PromptMessage is a message of a rendered prompt
+type PromptMessage struct {
+	Role    string       `json:"role"`
+	Content ContentBlock `json:"content"`
+}
+
+// PromptResult is the result of prompts/get
+type PromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// ListPrompts returns the prompts exposed by the server
+func (c *Client) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
+	var result struct {
+		Prompts []mcp.Prompt `json:"prompts"`
+	}
+	if err := c.Call(ctx, "prompts/list", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Prompts, nil
+}
+
+// GetPrompt renders a prompt with the given arguments
+func (c *Client) GetPrompt(ctx context.Context, name string, arguments map[string]string) (*PromptResult, error) {
+	var result PromptResult
+	if err := c.Call(ctx, "prompts/get", map[string]any{"name": name, "arguments": arguments}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,145 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// Syslog severities defined by MCP without a slog counterpart
+const (
+	LevelNotice    = slog.Level(2)
+	LevelCritical  = slog.Level(12)
+	LevelAlert     = slog.Level(16)
+	LevelEmergency = slog.Level(20)
+)
+
+// logLevels lists MCP log levels from the least to the most severe
+var logLevels = []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// logLevelSeverity returns the position of an MCP log level in logLevels
+func logLevelSeverity(level string) (int, bool) {
+	for i, candidate := range logLevels {
+		if candidate == level {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// mcpLogLevel maps a slog level to the MCP level it falls into
+func mcpLogLevel(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < LevelNotice:
+		return "info"
+	case level < slog.LevelWarn:
+		return "notice"
+	case level < slog.LevelError:
+		return "warning"
+	case level < LevelCritical:
+		return "error"
+	case level < LevelAlert:
+		return "critical"
+	case level < LevelEmergency:
+		return "alert"
+	default:
+		return "emergency"
+	}
+}
+
+type clientLoggerContextKey struct{}
+
+// ClientLogger returns a logger whose entries are sent to the client as notifications/message,
+// filtered by the level the client set with logging/setLevel. Nothing is sent before the client sets a level.
+func ClientLogger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(clientLoggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.New(&clientLogHandler{})
+}
+
+func contextWithClientLogger(ctx context.Context, session *Session, stream outboundStream, name string) context.Context {
+	logger := slog.New(&clientLogHandler{session: session, stream: stream, name: name})
+	return context.WithValue(ctx, clientLoggerContextKey{}, logger)
+}
+
+// clientLogHandler is a slog.Handler forwarding records to the client event stream
+type clientLogHandler struct {
+	session *Session
+	stream  outboundStream
+	name    string
+	attrs   []slog.Attr
+	group   string
+}
+
+func (h *clientLogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if h.session == nil || h.stream == nil {
+		return false
+	}
+	minimum, ok := logLevelSeverity(h.session.LogLevel())
+	if !ok {
+		return false
+	}
+	severity, _ := logLevelSeverity(mcpLogLevel(level))
+	return severity >= minimum
+}
+
+func (h *clientLogHandler) Handle(_ context.Context, record slog.Record) error {
+	data := map[string]any{"message": record.Message}
+	for _, attr := range h.attrs {
+		data[attr.Key] = attr.Value.Resolve().Any()
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		data[h.qualify(attr.Key)] = attr.Value.Resolve().Any()
+		return true
+	})
+
+	params := map[string]any{
+		"level": mcpLogLevel(record.Level),
+		"data":  data,
+	}
+	if h.name != "" {
+		params["logger"] = h.name
+	}
+	return h.stream.Notify("notifications/message", params)
+}
+
+func (h *clientLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, attr := range attrs {
+		clone.attrs = append(clone.attrs, slog.Attr{Key: h.qualify(attr.Key), Value: attr.Value})
+	}
+	return &clone
+}
+
+func (h *clientLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = h.qualify(name)
+	return &clone
+}
+
+func (h *clientLogHandler) qualify(key string) string {
+	if h.group == "" {
+		return key
+	}
+	return strings.Join([]string{h.group, key}, ".")
+}
+
+// handleSetLevel implements logging/setLevel for the session of the request
+func handleSetLevel(session *Session, level string) (any, error) {
+	if session == nil {
+		return nil, &JsonRPCError{Code: ErrInvalidRequest, Message: "logging/setLevel requires an initialized session"}
+	}
+	if _, ok := logLevelSeverity(level); !ok {
+		return nil, &JsonRPCError{Code: ErrInvalidParams, Message: "invalid log level: " + level, Data: map[string]any{"levels": logLevels}}
+	}
+	session.SetLogLevel(level)
+	return map[string]any{}, nil
+}
@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// responseMessages decodes every data line of an SSE body, or the body itself when it is plain JSON
+func responseMessages(t *testing.T, body io.Reader) []map[string]any {
+	t.Helper()
+	b, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	if trimmed := strings.TrimSpace(string(b)); strings.HasPrefix(trimmed, "{") {
+		var message map[string]any
+		if err := json.Unmarshal([]byte(trimmed), &message); err != nil {
+			t.Fatalf("invalid JSON body %q: %v", trimmed, err)
+		}
+		return []map[string]any{message}
+	}
+	var messages []map[string]any
+	for _, line := range strings.Split(string(b), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var message map[string]any
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &message); err != nil {
+			t.Fatalf("invalid SSE data %q: %v", line, err)
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func TestClientLoggingNotifications(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.RegisterTool(ToolDescription{
+		Name: "noisy",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			logger := ClientLogger(r.Context())
+			logger.Info("starting")
+			logger.Warn("almost out of quota", "remaining", 1)
+			return map[string]any{"ok": true}, nil
+		},
+	})
+
+	call := func(sessionID string, req MCPRequest) (*httptest.ResponseRecorder, []map[string]any) {
+		r := httptest.NewRequest("POST", "/", nil)
+		if sessionID != "" {
+			r.Header.Set(SessionHeader, sessionID)
+		}
+		w := httptest.NewRecorder()
+		body, err := server.Handle(r, w, req)
+		if err != nil {
+			t.Fatalf("Handle returned error: %v", err)
+		}
+		return w, responseMessages(t, body)
+	}
+
+	w, messages := call("", MCPRequest{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: MCPRequestParams{ProtocolVersion: "2025-06-18"}})
+	sessionID := w.Header().Get(SessionHeader)
+	if sessionID == "" {
+		t.Fatal("initialize should return a session id")
+	}
+	capabilities := messages[0]["result"].(map[string]any)["capabilities"].(map[string]any)
+	if _, ok := capabilities["logging"]; !ok {
+		t.Error("initialize should advertise the logging capability")
+	}
+
+	// Without a level nothing is sent
+	_, messages = call(sessionID, MCPRequest{JSONRPC: "2.0", ID: 2, Method: "tools/call", Params: MCPRequestParams{Name: "noisy"}})
+	if len(messages) != 1 {
+		t.Fatalf("expected only the result before setLevel, got %v", messages)
+	}
+
+	_, messages = call(sessionID, MCPRequest{JSONRPC: "2.0", ID: 3, Method: "logging/setLevel", Params: MCPRequestParams{Level: "notice"}})
+	if messages[0]["error"] != nil {
+		t.Fatalf("setLevel failed: %v", messages[0]["error"])
+	}
+
+	_, messages = call(sessionID, MCPRequest{JSONRPC: "2.0", ID: 4, Method: "tools/call", Params: MCPRequestParams{Name: "noisy"}})
+	if len(messages) != 2 {
+		t.Fatalf("expected one log notification and the result, got %v", messages)
+	}
+	if messages[0]["method"] != "notifications/message" {
+		t.Fatalf("expected a log notification first, got %v", messages[0])
+	}
+	params := messages[0]["params"].(map[string]any)
+	if params["level"] != "warning" || params["logger"] != "noisy" {
+		t.Errorf("unexpected notification params %v", params)
+	}
+	if data := params["data"].(map[string]any); data["message"] != "almost out of quota" || data["remaining"] != float64(1) {
+		t.Errorf("unexpected notification data %v", data)
+	}
+	if messages[1]["id"] != float64(4) {
+		t.Errorf("expected the result last, got %v", messages[1])
+	}
+}
+
+func TestSetLevelRejectsUnknownLevel(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(SessionHeader, initializeSession(t, server, nil))
+	body, err := server.Handle(r, httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: 1, Method: "logging/setLevel", Params: MCPRequestParams{Level: "verbose"}})
+	if err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+	messages := responseMessages(t, body)
+	rpcErr, ok := messages[0]["error"].(map[string]any)
+	if !ok || rpcErr["code"] != float64(ErrInvalidParams) {
+		t.Errorf("expected invalid params error, got %v", messages[0])
+	}
+}
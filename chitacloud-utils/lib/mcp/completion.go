@@ -0,0 +1,178 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MaxCompletionValues is the maximum number of values returned by completion/complete
+const MaxCompletionValues = 100
+
+// Reference types of completion/complete
+const (
+	RefPrompt   = "ref/prompt"
+	RefResource = "ref/resource"
+)
+
+// CompletionReference identifies the prompt or resource template being completed
+type CompletionReference struct {
+	Type string `json:"type"`
+	// Name is set for prompts
+	Name string `json:"name,omitempty"`
+	// URI is set for resource templates
+	URI string `json:"uri,omitempty"`
+}
+
+// CompletionArgument is the argument being completed and its partial value
+type CompletionArgument struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CompletionContext carries the values of the arguments already filled by the user
+type CompletionContext struct {
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// CompletionResult is the completion returned to the client
+type CompletionResult struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total"`
+	HasMore bool     `json:"hasMore"`
+}
+
+// CompletionProvider returns candidate values for an argument, given its partial value and the
+// other arguments already filled. Candidates not matching the partial value are filtered out and
+// the rest are ranked by the server, so providers may return their whole list.
+type CompletionProvider func(r *http.Request, argument CompletionArgument, arguments map[string]string) ([]string, error)
+
+// CompleteFrom returns a provider completing from a fixed list of values
+func CompleteFrom(values ...string) CompletionProvider {
+	return func(r *http.Request, argument CompletionArgument, arguments map[string]string) ([]string, error) {
+		return values, nil
+	}
+}
+
+type completionKey struct {
+	ref      CompletionReference
+	argument string
+}
+
+// RegisterPromptCompletion sets the provider completing an argument of a prompt, completions are only served
+// while the prompt is registered
+func (s *Server) RegisterPromptCompletion(prompt, argument string, provider CompletionProvider) error {
+	return s.registerCompletion(CompletionReference{Type: RefPrompt, Name: prompt}, argument, provider)
+}
+
+// RegisterResourceCompletion sets the provider completing a variable of a resource template, completions are
+// only served while the template is registered
+func (s *Server) RegisterResourceCompletion(uriTemplate, variable string, provider CompletionProvider) error {
+	return s.registerCompletion(CompletionReference{Type: RefResource, URI: uriTemplate}, variable, provider)
+}
+
+func (s *Server) registerCompletion(ref CompletionReference, argument string, provider CompletionProvider) error {
+	if argument == "" {
+		return errors.New("completion argument name is required")
+	}
+	if provider == nil {
+		return fmt.Errorf("completion of %s has no provider", argument)
+	}
+
+	s.completionsMu.Lock()
+	defer s.completionsMu.Unlock()
+	if s.completions == nil {
+		s.completions = map[completionKey]CompletionProvider{}
+	}
+	s.completions[completionKey{ref: ref, argument: argument}] = provider
+	return nil
+}
+
+// addCompletion registers provider under key unless another provider already has it
+func (s *Server) addCompletion(key completionKey, provider CompletionProvider) error {
+	s.completionsMu.Lock()
+	defer s.completionsMu.Unlock()
+	if _, ok := s.completions[key]; ok {
+		return fmt.Errorf("completion of %s in %s is already registered", key.argument, key.ref.Name+key.ref.URI)
+	}
+	if s.completions == nil {
+		s.completions = map[completionKey]CompletionProvider{}
+	}
+	s.completions[key] = provider
+	return nil
+}
+
+// completionProvider returns the provider registered for the argument of ref
+func (s *Server) completionProvider(ref CompletionReference, argument string) CompletionProvider {
+	s.completionsMu.RLock()
+	defer s.completionsMu.RUnlock()
+	// Only the name identifies a prompt and only the template URI identifies a resource
+	if ref.Type == RefPrompt {
+		ref.URI = ""
+	} else {
+		ref.Name = ""
+	}
+	return s.completions[completionKey{ref: ref, argument: argument}]
+}
+
+// handleComplete implements completion/complete
+func (s *Server) handleComplete(r *http.Request, params MCPRequestParams) (any, error) {
+	if params.Ref == nil || params.Argument == nil {
+		return nil, &JsonRPCError{Code: ErrInvalidParams, Message: "completion/complete requires ref and argument"}
+	}
+	switch params.Ref.Type {
+	case RefResource:
+		if s.FindResourceTemplate(params.Ref.URI) == nil {
+			return nil, &JsonRPCError{Code: ErrInvalidParams, Message: "resource template not found: " + params.Ref.URI}
+		}
+	case RefPrompt:
+		if s.FindPrompt(params.Ref.Name) == nil {
+			return nil, &JsonRPCError{Code: ErrInvalidParams, Message: "prompt not found: " + params.Ref.Name}
+		}
+	default:
+		return nil, &JsonRPCError{Code: ErrInvalidParams, Message: "unknown completion reference type: " + params.Ref.Type}
+	}
+
+	completion := CompletionResult{Values: []string{}}
+	if provider := s.completionProvider(*params.Ref, params.Argument.Name); provider != nil {
+		var arguments map[string]string
+		if params.Context != nil {
+			arguments = params.Context.Arguments
+		}
+		candidates, err := provider(r, *params.Argument, arguments)
+		if err != nil {
+			return nil, err
+		}
+		completion = rankCompletions(candidates, params.Argument.Value)
+	}
+	return map[string]any{"completion": completion}, nil
+}
+
+// rankCompletions keeps the candidates matching value, case-insensitively, listing values that start
+// with it before values that only contain it, and truncates the list to MaxCompletionValues
+func rankCompletions(candidates []string, value string) CompletionResult {
+	needle := strings.ToLower(value)
+	var prefixed, containing []string
+	for _, candidate := range candidates {
+		lower := strings.ToLower(candidate)
+		switch {
+		case strings.HasPrefix(lower, needle):
+			prefixed = append(prefixed, candidate)
+		case strings.Contains(lower, needle):
+			containing = append(containing, candidate)
+		}
+	}
+
+	values := append(prefixed, containing...)
+	result := CompletionResult{Values: values, Total: len(values)}
+	if result.Values == nil {
+		result.Values = []string{}
+	}
+	if len(values) > MaxCompletionValues {
+		result.Values = values[:MaxCompletionValues]
+		result.HasMore = true
+	}
+	return result
+}
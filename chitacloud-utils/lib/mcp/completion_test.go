@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func complete(t *testing.T, server *Server, params MCPRequestParams) map[string]any {
+	t.Helper()
+	body, err := server.Handle(httptest.NewRequest("POST", "/", nil), httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: 1, Method: "completion/complete", Params: params})
+	if err != nil {
+		t.Fatalf("completion/complete failed: %v", err)
+	}
+	defer body.Close()
+	return responseMessages(t, body)[0]
+}
+
+func TestCompleteTemplateVariable(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.RegisterResourceTemplate(hourTemplate())
+	server.RegisterResourceCompletion("hour://{timezone}", "timezone", CompleteFrom("Europe/Madrid", "Europe/Paris", "America/Los_Angeles", "Asia/Kolkata"))
+
+	response := complete(t, server, MCPRequestParams{
+		Ref:      &CompletionReference{Type: RefResource, URI: "hour://{timezone}"},
+		Argument: &CompletionArgument{Name: "timezone", Value: "pa"},
+	})
+	completion := response["result"].(map[string]any)["completion"].(map[string]any)
+	values := completion["values"].([]any)
+	if len(values) != 1 || values[0] != "Europe/Paris" {
+		t.Errorf("unexpected values %v", values)
+	}
+	if completion["total"] != float64(1) || completion["hasMore"] != false {
+		t.Errorf("unexpected completion %v", completion)
+	}
+}
+
+func TestCompleteResourceVariableUsesContext(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.RegisterResourceTemplate(ResourceTemplate{URITemplate: "repo://{owner}/{name}", Name: "repository", Handler: func(r *http.Request, uri string, variables map[string]string) ([]ResourceContents, error) {
+		return nil, nil
+	}})
+	server.RegisterResourceCompletion("repo://{owner}/{name}", "name", func(r *http.Request, argument CompletionArgument, arguments map[string]string) ([]string, error) {
+		return []string{arguments["owner"] + "-api", arguments["owner"] + "-web"}, nil
+	})
+
+	response := complete(t, server, MCPRequestParams{
+		Ref:      &CompletionReference{Type: RefResource, URI: "repo://{owner}/{name}"},
+		Argument: &CompletionArgument{Name: "name", Value: ""},
+		Context:  &CompletionContext{Arguments: map[string]string{"owner": "chita"}},
+	})
+	values := response["result"].(map[string]any)["completion"].(map[string]any)["values"].([]any)
+	if len(values) != 2 || values[0] != "chita-api" {
+		t.Errorf("unexpected values %v", values)
+	}
+}
+
+func TestCompleteUnknownArgumentIsEmpty(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.RegisterResourceTemplate(hourTemplate())
+	response := complete(t, server, MCPRequestParams{
+		Ref:      &CompletionReference{Type: RefResource, URI: "hour://{timezone}"},
+		Argument: &CompletionArgument{Name: "x", Value: "a"},
+	})
+	completion := response["result"].(map[string]any)["completion"].(map[string]any)
+	if len(completion["values"].([]any)) != 0 || completion["total"] != float64(0) {
+		t.Errorf("expected an empty completion, got %v", completion)
+	}
+
+	// Unregistered prompts and templates are not visible to clients, so they cannot be completed
+	for _, ref := range []CompletionReference{{Type: "ref/unknown"}, {Type: RefPrompt, Name: "get_hour"}, {Type: RefResource, URI: "missing://{x}"}} {
+		response = complete(t, server, MCPRequestParams{Ref: &ref, Argument: &CompletionArgument{Name: "x"}})
+		if response["error"] == nil {
+			t.Errorf("expected an error for %v, got %v", ref, response)
+		}
+	}
+}
+
+func TestCompletePromptArgument(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	if err := server.RegisterPrompt(hourPrompt()); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterPromptCompletion("plan_meeting", "timezone", CompleteFrom("Europe/Madrid", "Asia/Kolkata")); err != nil {
+		t.Fatal(err)
+	}
+
+	response := complete(t, server, MCPRequestParams{
+		Ref:      &CompletionReference{Type: RefPrompt, Name: "plan_meeting"},
+		Argument: &CompletionArgument{Name: "timezone", Value: "kol"},
+	})
+	values := response["result"].(map[string]any)["completion"].(map[string]any)["values"].([]any)
+	if len(values) != 1 || values[0] != "Asia/Kolkata" {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	server.UnregisterPrompt("plan_meeting")
+	response = complete(t, server, MCPRequestParams{
+		Ref:      &CompletionReference{Type: RefPrompt, Name: "plan_meeting"},
+		Argument: &CompletionArgument{Name: "timezone", Value: "kol"},
+	})
+	if response["error"] == nil {
+		t.Errorf("expected unregistered prompts to fail, got %v", response)
+	}
+}
+
+func TestRankCompletions(t *testing.T) {
+	result := rankCompletions([]string{"Asia/Tokyo", "America/Toronto", "Tonga", "Other"}, "to")
+	expected := []string{"Tonga", "Asia/Tokyo", "America/Toronto"}
+	if fmt.Sprint(result.Values) != fmt.Sprint(expected) || result.Total != 3 || result.HasMore {
+		t.Errorf("unexpected ranking %+v", result)
+	}
+
+	var many []string
+	for i := 0; i < MaxCompletionValues+20; i++ {
+		many = append(many, fmt.Sprintf("value-%d", i))
+	}
+	result = rankCompletions(many, "value")
+	if len(result.Values) != MaxCompletionValues || result.Total != MaxCompletionValues+20 || !result.HasMore {
+		t.Errorf("expected a truncated completion, got %d values, total %d", len(result.Values), result.Total)
+	}
+}
@@ -0,0 +1,121 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy configures the CORS headers of MCP responses and which browser origins may call the server
+type CORSPolicy struct {
+	// AllowedOrigins lists origins such as "https://app.example.com", "https://*.example.com",
+	// "http://localhost:*" or "*" for any origin
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// LoopbackOrigins matches pages served from the local machine on any port, such as MCP Inspector
+var LoopbackOrigins = []string{
+	"http://localhost", "http://localhost:*", "https://localhost", "https://localhost:*",
+	"http://127.0.0.1", "http://127.0.0.1:*", "https://127.0.0.1", "https://127.0.0.1:*",
+	`http://\[::1\]`, `http://\[::1\]:*`, `https://\[::1\]`, `https://\[::1\]:*`,
+}
+
+// DefaultCORSPolicy allows loopback origins with the headers used by the Streamable HTTP transport, which is
+// what MCP Inspector and local browser clients need. Other origins, or "*", must be allowed explicitly.
+func DefaultCORSPolicy() CORSPolicy {
+	return CORSPolicy{
+		AllowedOrigins: append([]string(nil), LoopbackOrigins...),
+		AllowedMethods: []string{"GET", "POST", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "Accept", SessionHeader, ProtocolVersionHeader, "Last-Event-ID", TraceparentHeader, TracestateHeader},
+		ExposedHeaders: []string{SessionHeader, ProtocolVersionHeader, "Retry-After"},
+	}
+}
+
+// SetCORSPolicy replaces the default CORS policy of the server
+func (s *Server) SetCORSPolicy(policy CORSPolicy) {
+	s.CORS = &policy
+}
+
+// corsPolicy returns the configured policy or the default one
+func (s *Server) corsPolicy() CORSPolicy {
+	if s.CORS != nil {
+		return *s.CORS
+	}
+	return DefaultCORSPolicy()
+}
+
+// AllowsOrigin reports whether origin matches one of the allowed origin patterns
+func (p CORSPolicy) AllowsOrigin(origin string) bool {
+	origin = strings.ToLower(strings.TrimSpace(origin))
+	for _, pattern := range p.AllowedOrigins {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if matched, err := path.Match(pattern, origin); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (p CORSPolicy) allowsAnyOrigin() bool {
+	for _, pattern := range p.AllowedOrigins {
+		if strings.TrimSpace(pattern) == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply sets the CORS headers for a request coming from origin, nothing is allowed for disallowed origins
+func (p CORSPolicy) Apply(w http.ResponseWriter, origin string) {
+	header := w.Header()
+
+	switch {
+	case p.allowsAnyOrigin() && !p.AllowCredentials:
+		header.Set("Access-Control-Allow-Origin", "*")
+	case origin != "" && p.AllowsOrigin(origin):
+		// Credentials cannot be combined with a wildcard, so the origin is echoed back
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Add("Vary", "Origin")
+	default:
+		header.Del("Access-Control-Allow-Origin")
+		return
+	}
+
+	if len(p.AllowedMethods) > 0 {
+		header.Set("Access-Control-Allow-Methods", strings.Join(p.AllowedMethods, ", "))
+	}
+	if len(p.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(p.AllowedHeaders, ", "))
+	}
+	if len(p.ExposedHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+	}
+	if p.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if p.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge.Seconds())))
+	}
+}
+
+// ValidateOrigin rejects browser requests from origins outside the policy, protecting
+// servers reachable on local or private addresses against DNS rebinding.
+// Requests without an Origin header do not come from a browser and are accepted.
+func (p CORSPolicy) ValidateOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" || p.AllowsOrigin(origin) {
+		return nil
+	}
+	return &JsonRPCError{Code: ErrInvalidRequest, Message: "origin not allowed: " + origin}
+}
@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func containsToken(list, token string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), token) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDefaultCORSPolicy(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+
+	r := httptest.NewRequest("OPTIONS", "/", nil)
+	r.Header.Set("Origin", "http://localhost:6274")
+	w := httptest.NewRecorder()
+	if _, err := server.Handle(r, w, MCPRequest{}); err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "http://localhost:6274" {
+		t.Errorf("default policy should allow loopback origins, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); !containsToken(got, SessionHeader) || !containsToken(got, ProtocolVersionHeader) {
+		t.Errorf("MCP headers should be allowed, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); !containsToken(got, SessionHeader) {
+		t.Errorf("session header should be exposed, got %q", got)
+	}
+
+	policy := DefaultCORSPolicy()
+	for origin, allowed := range map[string]bool{
+		"http://127.0.0.1:8080":             true,
+		"http://[::1]:3000":                 true,
+		"https://localhost":                 true,
+		"https://inspector.example.com":     false,
+		"http://localhost.attacker.example": false,
+	} {
+		if got := policy.AllowsOrigin(origin); got != allowed {
+			t.Errorf("default AllowsOrigin(%q) = %v, want %v", origin, got, allowed)
+		}
+	}
+
+	// A rebound page on another origin is rejected out of the box
+	r = httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Origin", "http://attacker.example")
+	w = httptest.NewRecorder()
+	if _, err := server.Handle(r, w, MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/list"}); err != nil || w.Code != 403 {
+		t.Errorf("expected 403 for a foreign origin, got %d %v", w.Code, err)
+	}
+}
+
+func TestCORSPolicyWildcardOptIn(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	policy := DefaultCORSPolicy()
+	policy.AllowedOrigins = []string{"*"}
+	server.SetCORSPolicy(policy)
+
+	r := httptest.NewRequest("OPTIONS", "/", nil)
+	r.Header.Set("Origin", "https://inspector.example.com")
+	w := httptest.NewRecorder()
+	if _, err := server.Handle(r, w, MCPRequest{}); err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("wildcard policy should allow any origin, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestCORSPolicyOriginPatterns(t *testing.T) {
+	policy := CORSPolicy{AllowedOrigins: []string{"https://*.chitacloud.com", "http://localhost:*"}}
+
+	for origin, allowed := range map[string]bool{
+		"https://app.chitacloud.com":      true,
+		"https://APP.chitacloud.com":      true,
+		"http://localhost:6274":           true,
+		"https://chitacloud.com.evil.com": false,
+		"http://app.chitacloud.com":       false,
+		"https://evil.com":                false,
+	} {
+		if got := policy.AllowsOrigin(origin); got != allowed {
+			t.Errorf("AllowsOrigin(%q) = %v, want %v", origin, got, allowed)
+		}
+	}
+}
+
+func TestCORSPolicyRejectsDisallowedOrigin(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetCORSPolicy(CORSPolicy{
+		AllowedOrigins:   []string{"https://app.chitacloud.com"},
+		AllowedHeaders:   []string{"Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	// Simulates a page on a rebound domain calling the server
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Origin", "http://attacker.example")
+	w := httptest.NewRecorder()
+	body, err := server.Handle(r, w, MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/list"})
+	if err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+	if w.Code != 403 {
+		t.Errorf("expected 403, got %d", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("disallowed origin should not get CORS headers")
+	}
+	messages := responseMessages(t, body)
+	if rpcErr, ok := messages[0]["error"].(map[string]any); !ok || rpcErr["code"] != float64(ErrInvalidRequest) {
+		t.Errorf("expected an invalid request error, got %v", messages[0])
+	}
+
+	r = httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Origin", "https://app.chitacloud.com")
+	w = httptest.NewRecorder()
+	if _, err := server.Handle(r, w, MCPRequest{JSONRPC: "2.0", ID: 2, Method: "tools/list"}); err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+	if w.Code != 200 {
+		t.Errorf("allowed origin should succeed, got %d", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.chitacloud.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("allowed origin should be echoed with credentials, got %v", w.Header())
+	}
+	if w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("expected max age 600, got %q", w.Header().Get("Access-Control-Max-Age"))
+	}
+
+	// Non-browser clients send no Origin
+	w = httptest.NewRecorder()
+	if _, err := server.Handle(httptest.NewRequest("POST", "/", nil), w, MCPRequest{JSONRPC: "2.0", ID: 3, Method: "tools/list"}); err != nil || w.Code != 200 {
+		t.Errorf("requests without origin should be accepted, got %d %v", w.Code, err)
+	}
+}
@@ -0,0 +1,138 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Actions a user can take on an elicitation request
+const (
+	ElicitationAccept  = "accept"
+	ElicitationDecline = "decline"
+	ElicitationCancel  = "cancel"
+)
+
+// ErrInvalidElicitation is returned when accepted content does not match the requested schema
+var ErrInvalidElicitation = errors.New("elicitation content does not match the requested schema")
+
+// ElicitationRequest holds the params of elicitation/create
+type ElicitationRequest struct {
+	Message string `json:"message"`
+	// RequestedSchema is a flat JSON Schema object whose properties are strings, numbers, integers, booleans or
+	// enums, see elicitationSchema
+	RequestedSchema map[string]any `json:"requestedSchema"`
+}
+
+// elicitationKeywords are the JSON Schema keywords MCP allows on the properties of requested schemas
+var elicitationKeywords = []string{"type", "title", "description", "default", "enum", "minLength", "maxLength", "format", "minimum", "maximum"}
+
+// ElicitationResult is the user's answer to an elicitation request
+type ElicitationResult struct {
+	Action  string         `json:"action"`
+	Content map[string]any `json:"content,omitempty"`
+}
+
+// Accepted reports whether the user accepted and submitted the requested content
+func (e *ElicitationResult) Accepted() bool {
+	return e.Action == ElicitationAccept
+}
+
+// Elicit asks the user, through the client, for input matching schema with elicitation/create.
+// It is meant to be called from tool handlers with r.Context() and blocks until the user answers.
+// Declined and cancelled requests are returned as results; accepted content is validated against schema.
+func Elicit(ctx context.Context, message string, schema *openapi3.Schema) (*ElicitationResult, error) {
+	if err := validateElicitationSchema(schema); err != nil {
+		return nil, err
+	}
+
+	session, err := sessionWithCapability(ctx, "elicitation")
+	if err != nil {
+		return nil, err
+	}
+
+	var result ElicitationResult
+	if err := session.Request(ctx, "elicitation/create", ElicitationRequest{Message: message, RequestedSchema: elicitationSchema(schema)}, &result); err != nil {
+		return nil, err
+	}
+
+	switch result.Action {
+	case ElicitationAccept:
+		if err := schema.VisitJSON(result.Content, openapi3.MultiErrors()); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidElicitation, err)
+		}
+	case ElicitationDecline, ElicitationCancel:
+		result.Content = nil
+	default:
+		return nil, fmt.Errorf("unknown elicitation action %q", result.Action)
+	}
+	return &result, nil
+}
+
+// Confirm asks the user a yes/no question, reporting true only when the user accepted and confirmed
+func Confirm(ctx context.Context, message string) (bool, error) {
+	schema := &openapi3.Schema{
+		Type: &openapi3.Types{openapi3.TypeObject},
+		Properties: openapi3.Schemas{
+			"confirm": &openapi3.SchemaRef{Value: &openapi3.Schema{
+				Type:        &openapi3.Types{openapi3.TypeBoolean},
+				Description: message,
+			}},
+		},
+		Required: []string{"confirm"},
+	}
+	result, err := Elicit(ctx, message, schema)
+	if err != nil {
+		return false, err
+	}
+	confirmed, _ := result.Content["confirm"].(bool)
+	return result.Accepted() && confirmed, nil
+}
+
+// validateElicitationSchema checks the restrictions MCP puts on requested schemas
+func validateElicitationSchema(schema *openapi3.Schema) error {
+	if schema == nil || schema.Type == nil || !schema.Type.Is(openapi3.TypeObject) {
+		return errors.New("elicitation schema must be an object")
+	}
+	for name, property := range schema.Properties {
+		if property == nil || property.Value == nil || property.Value.Type == nil {
+			return fmt.Errorf("elicitation property %s needs a type", name)
+		}
+		value := property.Value
+		types := value.Type
+		if !types.Is(openapi3.TypeString) && !types.Is(openapi3.TypeNumber) && !types.Is(openapi3.TypeInteger) && !types.Is(openapi3.TypeBoolean) {
+			return fmt.Errorf("elicitation property %s must be a string, number, integer or boolean", name)
+		}
+		if value.Nullable || len(value.AllOf) > 0 || len(value.AnyOf) > 0 || len(value.OneOf) > 0 || value.Not != nil {
+			return fmt.Errorf("elicitation property %s must be a single primitive type", name)
+		}
+		if len(value.Enum) > 0 && !types.Is(openapi3.TypeString) {
+			return fmt.Errorf("elicitation property %s can only enumerate strings", name)
+		}
+	}
+	return nil
+}
+
+// elicitationSchema converts a validated schema to the JSON Schema of elicitation/create. Properties keep the
+// keywords MCP allows, the content is still validated against the whole schema.
+func elicitationSchema(schema *openapi3.Schema) map[string]any {
+	properties := make(map[string]any, len(schema.Properties))
+	for name, property := range schema.Properties {
+		converted := JSONSchema(property.Value)
+		allowed := make(map[string]any, len(converted))
+		for _, keyword := range elicitationKeywords {
+			if value, ok := converted[keyword]; ok {
+				allowed[keyword] = value
+			}
+		}
+		properties[name] = allowed
+	}
+	requested := map[string]any{"type": "object", "properties": properties}
+	if len(schema.Required) > 0 {
+		requested["required"] = append([]string{}, schema.Required...)
+	}
+	return requested
+}
@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func invitationSchema() *openapi3.Schema {
+	return &openapi3.Schema{
+		Type: &openapi3.Types{openapi3.TypeObject},
+		Properties: openapi3.Schemas{
+			"email": &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, Format: "email", Pattern: "@example\\.com$"}},
+			"seats": &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeInteger}}},
+		},
+		Required: []string{"email"},
+	}
+}
+
+// elicitingServer registers a tool that elicits an invitation and stores the outcome
+func elicitingServer(result **ElicitationResult, elicitErr *error) *Server {
+	server := NewServer("test", "1.0", "test")
+	server.SetStreaming(true)
+	server.RegisterTool(ToolDescription{
+		Name: "invite",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			*result, *elicitErr = Elicit(r.Context(), "Who should be invited?", invitationSchema())
+			return map[string]any{}, nil
+		},
+	})
+	return server
+}
+
+func TestElicitAccept(t *testing.T) {
+	var result *ElicitationResult
+	var elicitErr error
+	server := elicitingServer(&result, &elicitErr)
+
+	sessionID := initializeSession(t, server, map[string]any{"elicitation": map[string]any{}})
+	reader := callToolStreaming(t, server, sessionID, 5, "invite")
+
+	request := readStreamMessage(t, reader)
+	if request["method"] != "elicitation/create" {
+		t.Fatalf("expected an elicitation request, got %v", request)
+	}
+	params := request["params"].(map[string]any)
+	if params["message"] != "Who should be invited?" {
+		t.Errorf("unexpected elicitation params %v", params)
+	}
+	// The schema is sent as JSON Schema with the keywords MCP allows on properties
+	requested := params["requestedSchema"].(map[string]any)
+	properties, _ := requested["properties"].(map[string]any)
+	email, _ := properties["email"].(map[string]any)
+	if _, ok := requested["$schema"]; ok || requested["type"] != "object" || requested["required"].([]any)[0] != "email" {
+		t.Errorf("unexpected requested schema %v", requested)
+	}
+	if email["type"] != "string" || email["format"] != "email" || email["pattern"] != nil {
+		t.Errorf("unexpected email property %v", email)
+	}
+
+	postClientResponse(t, server, sessionID, int(request["id"].(float64)), ElicitationResult{
+		Action:  ElicitationAccept,
+		Content: map[string]any{"email": "ada@example.com", "seats": 2},
+	})
+	readStreamMessage(t, reader)
+
+	if elicitErr != nil {
+		t.Fatalf("unexpected error: %v", elicitErr)
+	}
+	if !result.Accepted() || result.Content["email"] != "ada@example.com" {
+		t.Errorf("unexpected elicitation result %+v", result)
+	}
+}
+
+func TestElicitRejectsInvalidContent(t *testing.T) {
+	var result *ElicitationResult
+	var elicitErr error
+	server := elicitingServer(&result, &elicitErr)
+
+	sessionID := initializeSession(t, server, map[string]any{"elicitation": map[string]any{}})
+	reader := callToolStreaming(t, server, sessionID, 6, "invite")
+
+	request := readStreamMessage(t, reader)
+	postClientResponse(t, server, sessionID, int(request["id"].(float64)), ElicitationResult{
+		Action:  ElicitationAccept,
+		Content: map[string]any{"seats": "many"},
+	})
+	readStreamMessage(t, reader)
+
+	if !errors.Is(elicitErr, ErrInvalidElicitation) {
+		t.Errorf("expected ErrInvalidElicitation, got %v", elicitErr)
+	}
+}
+
+func TestElicitDeclineAndCancel(t *testing.T) {
+	for i, action := range []string{ElicitationDecline, ElicitationCancel} {
+		var result *ElicitationResult
+		var elicitErr error
+		server := elicitingServer(&result, &elicitErr)
+
+		sessionID := initializeSession(t, server, map[string]any{"elicitation": map[string]any{}})
+		reader := callToolStreaming(t, server, sessionID, 7+i, "invite")
+
+		request := readStreamMessage(t, reader)
+		postClientResponse(t, server, sessionID, int(request["id"].(float64)), map[string]any{
+			"action":  action,
+			"content": map[string]any{"email": "ignored@example.com"},
+		})
+		readStreamMessage(t, reader)
+
+		if elicitErr != nil {
+			t.Fatalf("%s: unexpected error: %v", action, elicitErr)
+		}
+		if result.Action != action || result.Accepted() || result.Content != nil {
+			t.Errorf("%s: unexpected elicitation result %+v", action, result)
+		}
+	}
+}
+
+func TestElicitValidatesSchema(t *testing.T) {
+	nested := &openapi3.Schema{
+		Type: &openapi3.Types{openapi3.TypeObject},
+		Properties: openapi3.Schemas{
+			"address": &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeObject}}},
+		},
+	}
+	if err := validateElicitationSchema(nested); err == nil {
+		t.Error("expected nested objects to be rejected")
+	}
+	for name, property := range map[string]*openapi3.Schema{
+		"nullable":   {Type: &openapi3.Types{openapi3.TypeString}, Nullable: true},
+		"union":      {Type: &openapi3.Types{openapi3.TypeString, openapi3.TypeInteger}},
+		"anyOf":      {Type: &openapi3.Types{openapi3.TypeString}, AnyOf: openapi3.SchemaRefs{{Value: openapi3.NewStringSchema()}}},
+		"numberEnum": {Type: &openapi3.Types{openapi3.TypeInteger}, Enum: []any{1, 2}},
+	} {
+		schema := openapi3.NewObjectSchema().WithProperty("field", property)
+		if err := validateElicitationSchema(schema); err == nil {
+			t.Errorf("expected the %s property to be rejected", name)
+		}
+	}
+	if err := validateElicitationSchema(invitationSchema()); err != nil {
+		t.Errorf("unexpected error for a flat schema: %v", err)
+	}
+}
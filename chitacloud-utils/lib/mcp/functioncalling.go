@@ -0,0 +1,392 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxFunctionNameLength is the longest tool name accepted by OpenAI and Anthropic
+const maxFunctionNameLength = 64
+
+var invalidFunctionName = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// OpenAITool is a tool definition of the OpenAI chat completions API
+type OpenAITool struct {
+	Type     string         `json:"type"`
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIFunction describes a function the model can call
+type OpenAIFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+	Strict      bool           `json:"strict,omitempty"`
+}
+
+// OpenAIToolCall is a function call requested by an OpenAI model, Arguments is a JSON encoded object
+type OpenAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// OpenAIToolMessage is the tool message answering an OpenAIToolCall
+type OpenAIToolMessage struct {
+	Role       string `json:"role"`
+	ToolCallID string `json:"tool_call_id"`
+	Content    string `json:"content"`
+}
+
+// AnthropicTool is a tool definition of the Anthropic messages API
+type AnthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+// AnthropicToolUse is a tool_use content block requested by an Anthropic model
+type AnthropicToolUse struct {
+	Type  string         `json:"type"`
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Input map[string]any `json:"input"`
+}
+
+// AnthropicToolResult is the tool_result content block answering an AnthropicToolUse
+type AnthropicToolResult struct {
+	Type      string `json:"type"`
+	ToolUseID string `json:"tool_use_id"`
+	Content   string `json:"content"`
+	IsError   bool   `json:"is_error,omitempty"`
+}
+
+// FunctionName is the name of a tool in provider formats, which only allow letters, digits, _ and -
+func FunctionName(tool string) string {
+	name := invalidFunctionName.ReplaceAllString(tool, "_")
+	if len(name) > maxFunctionNameLength {
+		name = name[:maxFunctionNameLength]
+	}
+	return name
+}
+
+// ErrFunctionNameConflict is returned when several tools share the same name in provider formats
+var ErrFunctionNameConflict = errors.New("function name conflict")
+
+// functionTools returns the registered tools in registration order with the function names shared by several
+// tools, e.g. hour.get_time and hour_get_time, mapped to the conflict error
+func (s *Server) functionTools() ([]ToolDescription, map[string]error) {
+	tools := s.Tools()
+	owners := make(map[string][]string, len(tools))
+	for _, tool := range tools {
+		name := FunctionName(tool.Name)
+		owners[name] = append(owners[name], tool.Name)
+	}
+	conflicts := map[string]error{}
+	for name, names := range owners {
+		if len(names) > 1 {
+			conflicts[name] = fmt.Errorf("%w: %s are all exported as %s", ErrFunctionNameConflict, strings.Join(names, ", "), name)
+		}
+	}
+	return tools, conflicts
+}
+
+// exportedFunctions returns the tools that can be exported, tools whose function name collides are left out and
+// reported in the error
+func (s *Server) exportedFunctions() ([]ToolDescription, error) {
+	tools, conflicts := s.functionTools()
+	exported := make([]ToolDescription, 0, len(tools))
+	reported := map[string]struct{}{}
+	var errs []error
+	for _, tool := range tools {
+		name := FunctionName(tool.Name)
+		if err, ok := conflicts[name]; ok {
+			if _, seen := reported[name]; !seen {
+				reported[name] = struct{}{}
+				errs = append(errs, err)
+			}
+			continue
+		}
+		exported = append(exported, tool)
+	}
+	return exported, errors.Join(errs...)
+}
+
+// OpenAITools renders the registered tools as the tools of an OpenAI chat completions request. Tools are
+// exported in strict mode when their schema allows it. Tools sharing a function name are left out and reported
+// in the error, the other tools are still returned.
+func (s *Server) OpenAITools() ([]OpenAITool, error) {
+	tools, err := s.exportedFunctions()
+	exported := make([]OpenAITool, 0, len(tools))
+	for _, tool := range tools {
+		function := OpenAIFunction{Name: FunctionName(tool.Name), Description: tool.Description}
+		parameters := functionParameters(tool.InputSchema)
+		if strictSchema(parameters) {
+			function.Parameters, function.Strict = parameters, true
+		} else {
+			function.Parameters = functionParameters(tool.InputSchema)
+		}
+		exported = append(exported, OpenAITool{Type: "function", Function: function})
+	}
+	return exported, err
+}
+
+// AnthropicTools renders the registered tools as the tools of an Anthropic messages request. Tools sharing a
+// function name are left out and reported in the error, the other tools are still returned.
+func (s *Server) AnthropicTools() ([]AnthropicTool, error) {
+	tools, err := s.exportedFunctions()
+	exported := make([]AnthropicTool, 0, len(tools))
+	for _, tool := range tools {
+		exported = append(exported, AnthropicTool{
+			Name:        FunctionName(tool.Name),
+			Description: tool.Description,
+			InputSchema: functionParameters(tool.InputSchema),
+		})
+	}
+	return exported, err
+}
+
+// functionParameters converts an input schema to the JSON Schema object both providers require: the root
+// must be an object with properties and the $schema keyword is not accepted
+func functionParameters(schema *openapi3.Schema) map[string]any {
+	if schema == nil {
+		schema = openapi3.NewObjectSchema()
+	}
+	parameters := JSONSchema(schema)
+	delete(parameters, "$schema")
+	parameters["type"] = "object"
+	if _, ok := parameters["properties"]; !ok {
+		parameters["properties"] = map[string]any{}
+	}
+	return parameters
+}
+
+// strictSchema rewrites a converted schema in place for OpenAI strict mode: objects forbid additional
+// properties and require every property, optional ones become nullable. It reports false, leaving the schema
+// partly rewritten, when the schema uses keywords strict mode rejects.
+func strictSchema(schema map[string]any) bool {
+	for _, keyword := range []string{"allOf", "oneOf", "not", "patternProperties"} {
+		if _, ok := schema[keyword]; ok {
+			return false
+		}
+	}
+	// Strict mode has no schema accepting any value
+	if !hasAnyKey(schema, "type", "enum", "anyOf", "$ref") {
+		return false
+	}
+
+	if properties, ok := schema["properties"].(map[string]any); ok || schema["type"] == "object" {
+		if additional, ok := schema["additionalProperties"]; ok && additional != false {
+			return false
+		}
+		schema["additionalProperties"] = false
+
+		required := map[string]bool{}
+		if names, ok := schema["required"].([]string); ok {
+			for _, name := range names {
+				required[name] = true
+			}
+		}
+		names := make([]string, 0, len(properties))
+		for name, property := range properties {
+			property, ok := property.(map[string]any)
+			if !ok || !strictSchema(property) {
+				return false
+			}
+			if !required[name] {
+				properties[name] = nullableSchema(property)
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		schema["required"] = names
+	}
+
+	if items, ok := schema["items"].(map[string]any); ok && !strictSchema(items) {
+		return false
+	}
+	if variants, ok := schema["anyOf"].([]any); ok {
+		for _, variant := range variants {
+			if variant, ok := variant.(map[string]any); !ok || !strictSchema(variant) {
+				return false
+			}
+		}
+	}
+	if defs, ok := schema["$defs"].(map[string]any); ok {
+		for _, def := range defs {
+			if def, ok := def.(map[string]any); !ok || !strictSchema(def) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasAnyKey(schema map[string]any, keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := schema[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nullableSchema makes a converted schema accept null, through its type when it has one
+func nullableSchema(schema map[string]any) map[string]any {
+	switch types := schema["type"].(type) {
+	case string:
+		if types != "null" {
+			schema["type"] = []string{types, "null"}
+		}
+	case []string:
+		if !slices.Contains(types, "null") {
+			schema["type"] = append(types, "null")
+		}
+	default:
+		if variants, ok := schema["anyOf"].([]any); ok && len(schema) == 1 {
+			schema["anyOf"] = append(variants, map[string]any{"type": "null"})
+			return schema
+		}
+		return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !containsNil(enum) {
+		schema["enum"] = append(enum, nil)
+	}
+	return schema
+}
+
+// dropOptionalNulls removes the null arguments a strict schema forced the model to send for optional
+// properties that do not accept null
+func dropOptionalNulls(schema *openapi3.Schema, arguments map[string]any) {
+	if schema == nil {
+		return
+	}
+	for name, value := range arguments {
+		property := schema.Properties[name]
+		if property == nil || property.Value == nil {
+			continue
+		}
+		switch value := value.(type) {
+		case nil:
+			if !property.Value.Nullable && !slices.Contains(schema.Required, name) {
+				delete(arguments, name)
+			}
+		case map[string]any:
+			dropOptionalNulls(property.Value, value)
+		case []any:
+			if property.Value.Items == nil {
+				continue
+			}
+			for _, item := range value {
+				if item, ok := item.(map[string]any); ok {
+					dropOptionalNulls(property.Value.Items.Value, item)
+				}
+			}
+		}
+	}
+}
+
+// CallOpenAITool runs the tool requested by an OpenAI model and returns the tool message to send back,
+// failures are reported to the model in the message content
+func (s *Server) CallOpenAITool(r *http.Request, call OpenAIToolCall) OpenAIToolMessage {
+	message := OpenAIToolMessage{Role: "tool", ToolCallID: call.ID}
+
+	arguments := map[string]any{}
+	if strings.TrimSpace(call.Function.Arguments) != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &arguments); err != nil {
+			message.Content = "Error: arguments must be a JSON object: " + err.Error()
+			return message
+		}
+	}
+
+	content, isError := s.callFunction(r, call.Function.Name, arguments)
+	if isError {
+		content = "Error: " + content
+	}
+	message.Content = content
+	return message
+}
+
+// CallAnthropicTool runs the tool requested by an Anthropic model and returns the tool_result block to send back
+func (s *Server) CallAnthropicTool(r *http.Request, use AnthropicToolUse) AnthropicToolResult {
+	content, isError := s.callFunction(r, use.Name, use.Input)
+	return AnthropicToolResult{Type: "tool_result", ToolUseID: use.ID, Content: content, IsError: isError}
+}
+
+// callFunction runs the tool exported as name and renders its result, or its error, as text. Calls go through
+// the same rate limits as tools/call, and null arguments of optional properties are dropped as strict OpenAI
+// schemas make the model send them.
+func (s *Server) callFunction(r *http.Request, name string, arguments map[string]any) (string, bool) {
+	tools, conflicts := s.functionTools()
+	if err, ok := conflicts[name]; ok {
+		return err.Error(), true
+	}
+	var tool *ToolDescription
+	for _, candidate := range tools {
+		if FunctionName(candidate.Name) == name {
+			tool = &candidate
+			break
+		}
+	}
+	if tool == nil {
+		return fmt.Sprintf("%s: %s", ErrToolNotFound, name), true
+	}
+
+	dropOptionalNulls(tool.InputSchema, arguments)
+
+	req := MCPRequest{JSONRPC: "2.0", Method: "tools/call", Params: MCPRequestParams{Name: tool.Name, Arguments: arguments}}
+	logger := s.logger().With("tool", tool.Name)
+	r = r.WithContext(s.requestContext(r.Context(), logger, nil, &eventStream{}, req))
+	if err := s.checkRateLimit(r, nil, req); err != nil {
+		return rpcError(err, nil).Message, true
+	}
+
+	result, _, err := s.callTool(r, tool.Name, arguments)
+	if err != nil {
+		return rpcError(err, nil).Message, true
+	}
+	return functionResultText(result)
+}
+
+// functionResultText renders a tool result as the text of a provider tool message
+func functionResultText(result any) (string, bool) {
+	if text, ok := result.(string); ok {
+		return text, false
+	}
+	if isCallToolResult(result) {
+		// Tools returning their own CallToolResult already chose the text the model sees
+		var callResult struct {
+			Content []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+			IsError bool `json:"isError"`
+		}
+		if encoded, err := json.Marshal(result); err == nil && json.Unmarshal(encoded, &callResult) == nil {
+			var texts []string
+			for _, block := range callResult.Content {
+				if block.Type == "text" {
+					texts = append(texts, block.Text)
+				}
+			}
+			return strings.Join(texts, "\n"), callResult.IsError
+		}
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return "failed to encode tool result: " + err.Error(), true
+	}
+	return string(encoded), false
+}
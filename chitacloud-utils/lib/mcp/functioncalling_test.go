@@ -0,0 +1,209 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func functionTestServer() *Server {
+	server := NewServer("test", "1.0", "test")
+	server.RegisterTool(ToolDescription{
+		Name:        "weather.current",
+		Description: "Current weather of a city",
+		InputSchema: openapi3.NewObjectSchema().
+			WithProperty("city", openapi3.NewStringSchema()).
+			WithProperty("units", &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, Nullable: true}).
+			WithProperty("days", openapi3.NewIntegerSchema()).
+			WithRequired([]string{"city"}),
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			if params["city"] == "Atlantis" {
+				return nil, errors.New("city not found")
+			}
+			if _, ok := params["days"]; ok {
+				return map[string]any{"city": params["city"], "days": params["days"]}, nil
+			}
+			return map[string]any{"city": params["city"], "celsius": 21}, nil
+		},
+	})
+	server.RegisterTool(ToolDescription{
+		Name:        "annotate",
+		InputSchema: openapi3.NewObjectSchema().WithProperty("labels", openapi3.NewObjectSchema().WithAnyAdditionalProperties()),
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return params, nil
+		},
+	})
+	server.RegisterTool(ToolDescription{
+		Name: "summary",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return map[string]any{"content": []any{map[string]any{"type": "text", "text": "all good"}}}, nil
+		},
+	})
+	return server
+}
+
+func TestOpenAITools(t *testing.T) {
+	exported, err := functionTestServer().OpenAITools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := json.Marshal(exported)
+	var tools []map[string]any
+	json.Unmarshal(encoded, &tools)
+
+	if len(tools) != 3 || tools[0]["type"] != "function" {
+		t.Fatalf("unexpected tools %s", encoded)
+	}
+	function := tools[0]["function"].(map[string]any)
+	if function["name"] != "weather_current" || function["description"] != "Current weather of a city" || function["strict"] != true {
+		t.Errorf("unexpected function %v", function)
+	}
+	parameters := function["parameters"].(map[string]any)
+	if _, ok := parameters["$schema"]; ok || parameters["type"] != "object" || parameters["additionalProperties"] != false {
+		t.Errorf("unexpected parameters %v", parameters)
+	}
+	if required, _ := parameters["required"].([]any); len(required) != 3 {
+		t.Errorf("expected strict mode to require every property, got %v", parameters["required"])
+	}
+	properties := parameters["properties"].(map[string]any)
+	if city := properties["city"].(map[string]any); city["type"] != "string" {
+		t.Errorf("expected required properties to stay non-nullable, got %v", city)
+	}
+	for _, name := range []string{"units", "days"} {
+		if types, _ := properties[name].(map[string]any)["type"].([]any); len(types) != 2 || types[1] != "null" {
+			t.Errorf("expected optional property %s to be nullable, got %v", name, properties[name])
+		}
+	}
+
+	empty := tools[2]["function"].(map[string]any)
+	if parameters := empty["parameters"].(map[string]any); empty["strict"] != true || parameters["properties"] == nil || parameters["additionalProperties"] != false {
+		t.Errorf("expected a strict empty object schema, got %v", empty)
+	}
+
+	// Free-form objects cannot be described in strict mode
+	annotate := tools[1]["function"].(map[string]any)
+	if _, ok := annotate["strict"]; ok {
+		t.Errorf("expected a non-strict function, got %v", annotate)
+	}
+	if parameters := annotate["parameters"].(map[string]any); parameters["additionalProperties"] != nil || parameters["required"] != nil {
+		t.Errorf("expected the schema as declared, got %v", parameters)
+	}
+}
+
+func TestAnthropicTools(t *testing.T) {
+	exported, err := functionTestServer().AnthropicTools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := json.Marshal(exported)
+	var tools []map[string]any
+	json.Unmarshal(encoded, &tools)
+
+	if len(tools) != 3 || tools[0]["name"] != "weather_current" {
+		t.Fatalf("unexpected tools %s", encoded)
+	}
+	schema := tools[0]["input_schema"].(map[string]any)
+	if schema["type"] != "object" || len(schema["required"].([]any)) != 1 || schema["additionalProperties"] != nil {
+		t.Errorf("unexpected input schema %v", schema)
+	}
+}
+
+func TestCallOpenAITool(t *testing.T) {
+	server := functionTestServer()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	call := OpenAIToolCall{ID: "call_1", Type: "function"}
+	call.Function.Name = "weather_current"
+	call.Function.Arguments = `{"city": "Madrid"}`
+	message := server.CallOpenAITool(r, call)
+	if message.Role != "tool" || message.ToolCallID != "call_1" {
+		t.Errorf("unexpected message %+v", message)
+	}
+	var result map[string]any
+	if err := json.Unmarshal([]byte(message.Content), &result); err != nil || result["city"] != "Madrid" {
+		t.Errorf("expected the JSON result as content, got %q", message.Content)
+	}
+
+	// Strict schemas make the model send null for optional arguments
+	call.Function.Arguments = `{"city": "Madrid", "units": null, "days": null}`
+	result = nil
+	if err := json.Unmarshal([]byte(server.CallOpenAITool(r, call).Content), &result); err != nil || result["celsius"] == nil {
+		t.Errorf("expected null optional arguments to be dropped, got %v", result)
+	}
+
+	call.Function.Arguments = `{"city": "Atlantis"}`
+	if message := server.CallOpenAITool(r, call); message.Content != "Error: city not found" {
+		t.Errorf("expected the tool error, got %q", message.Content)
+	}
+
+	call.Function.Arguments = `not json`
+	if message := server.CallOpenAITool(r, call); message.ToolCallID != "call_1" || message.Content[:6] != "Error:" {
+		t.Errorf("expected an argument error, got %+v", message)
+	}
+}
+
+func TestCallAnthropicTool(t *testing.T) {
+	server := functionTestServer()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	result := server.CallAnthropicTool(r, AnthropicToolUse{Type: "tool_use", ID: "toolu_1", Name: "summary"})
+	if result.Type != "tool_result" || result.ToolUseID != "toolu_1" || result.Content != "all good" || result.IsError {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	result = server.CallAnthropicTool(r, AnthropicToolUse{Type: "tool_use", ID: "toolu_2", Name: "weather_current", Input: map[string]any{"city": "Atlantis"}})
+	if !result.IsError || result.Content != "city not found" {
+		t.Errorf("expected an error result, got %+v", result)
+	}
+
+	result = server.CallAnthropicTool(r, AnthropicToolUse{Type: "tool_use", ID: "toolu_3", Name: "missing"})
+	if !result.IsError {
+		t.Errorf("expected unknown tools to fail, got %+v", result)
+	}
+}
+
+func TestFunctionNameConflicts(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.RegisterTool(namedTool("hour.get_time", "dotted"))
+	server.RegisterTool(namedTool("hour_get_time", "underscored"))
+	server.RegisterTool(namedTool("hour.zones", "zones"))
+
+	// Only the colliding tools are left out
+	openAI, err := server.OpenAITools()
+	if !errors.Is(err, ErrFunctionNameConflict) || len(openAI) != 1 || openAI[0].Function.Name != "hour_zones" {
+		t.Errorf("expected a function name conflict next to the other tools, got %+v, %v", openAI, err)
+	}
+	anthropic, err := server.AnthropicTools()
+	if !errors.Is(err, ErrFunctionNameConflict) || len(anthropic) != 1 || anthropic[0].Name != "hour_zones" {
+		t.Errorf("expected a function name conflict next to the other tools, got %+v, %v", anthropic, err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	result := server.CallAnthropicTool(r, AnthropicToolUse{Type: "tool_use", ID: "toolu_1", Name: "hour_get_time"})
+	if !result.IsError || !strings.Contains(result.Content, ErrFunctionNameConflict.Error()) {
+		t.Errorf("expected ambiguous calls to fail, got %+v", result)
+	}
+	result = server.CallAnthropicTool(r, AnthropicToolUse{Type: "tool_use", ID: "toolu_2", Name: "hour_zones"})
+	if result.IsError || result.Content != "zones" {
+		t.Errorf("expected the other tools to be callable, got %+v", result)
+	}
+}
+
+func TestFunctionCallsAreRateLimited(t *testing.T) {
+	server := functionTestServer()
+	server.SetRateLimiter(NewRateLimiter(RateLimit{Rate: 0.001, Burst: 1}))
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	if result := server.CallAnthropicTool(r, AnthropicToolUse{Type: "tool_use", ID: "toolu_1", Name: "summary"}); result.IsError {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	result := server.CallAnthropicTool(r, AnthropicToolUse{Type: "tool_use", ID: "toolu_2", Name: "summary"})
+	if !result.IsError || !strings.Contains(result.Content, "rate limit") {
+		t.Errorf("expected the second call to be rate limited, got %+v", result)
+	}
+}
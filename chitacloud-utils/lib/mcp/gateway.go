@@ -0,0 +1,203 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MountSeparator joins the prefix of a mounted server and the names of its tools
+const MountSeparator = "."
+
+// ErrMountConflict is returned when a mounted server exposes a tool or prompt name, resource URI, resource
+// template or completion already taken
+var ErrMountConflict = errors.New("mount conflict")
+
+// Mount exposes the tools, prompts, resources, resource templates and completions of backend on s, turning s
+// into a gateway for several servers. Tools and prompts are renamed prefix.name and calls are routed to backend,
+// which applies its own caching, argument and result limits; resource URIs are kept as they are and
+// backend.PublishResourceUpdated also notifies the clients subscribed through s. Every conflict is reported at
+// once and nothing is mounted when there is one. Tools and resources added to backend after mounting are not
+// exposed. Servers deployed separately are mounted through client.Backend.
+func (s *Server) Mount(prefix string, backend *Server) error {
+	if backend == nil || backend == s {
+		return errors.New("mount requires a distinct backend server")
+	}
+	if strings.Contains(prefix, "/") {
+		return fmt.Errorf("mount prefix %q cannot contain /", prefix)
+	}
+	if s.isMountedOn(backend) {
+		return fmt.Errorf("mounting %s on %s would create a cycle", backend.Name, s.Name)
+	}
+
+	tools := backend.Tools()
+	prompts := backend.Prompts()
+	resources := backend.Resources()
+	templates := backend.ResourceTemplates()
+	backend.completionsMu.RLock()
+	completions := make(map[completionKey]CompletionProvider, len(backend.completions))
+	for key, provider := range backend.completions {
+		if key.ref.Type == RefPrompt {
+			key.ref.Name = mountedName(prefix, key.ref.Name)
+		}
+		completions[key] = provider
+	}
+	backend.completionsMu.RUnlock()
+
+	var conflicts []error
+	for _, tool := range tools {
+		if s.FindTool(mountedName(prefix, tool.Name)) != nil {
+			conflicts = append(conflicts, fmt.Errorf("%w: tool %s", ErrMountConflict, mountedName(prefix, tool.Name)))
+		}
+	}
+	for _, prompt := range prompts {
+		if s.FindPrompt(mountedName(prefix, prompt.Name)) != nil {
+			conflicts = append(conflicts, fmt.Errorf("%w: prompt %s", ErrMountConflict, mountedName(prefix, prompt.Name)))
+		}
+	}
+	for _, resource := range resources {
+		if s.FindResource(resource.URI) != nil {
+			conflicts = append(conflicts, fmt.Errorf("%w: resource %s", ErrMountConflict, resource.URI))
+		}
+	}
+	for _, template := range templates {
+		if s.FindResourceTemplate(template.URITemplate) != nil {
+			conflicts = append(conflicts, fmt.Errorf("%w: resource template %s", ErrMountConflict, template.URITemplate))
+		}
+	}
+	for key := range completions {
+		if s.completionProvider(key.ref, key.argument) != nil {
+			conflicts = append(conflicts, fmt.Errorf("%w: completion of %s in %s", ErrMountConflict, key.argument, key.ref.Name+key.ref.URI))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("failed to mount %s: %w", backend.Name, errors.Join(conflicts...))
+	}
+
+	// Registrations racing with the mount can still conflict, the mount is then rolled back
+	var mounted mountedParts
+	err := func() error {
+		for _, tool := range tools {
+			tool = mountedTool(prefix, backend, tool)
+			if err := s.RegisterTool(tool); err != nil {
+				return err
+			}
+			mounted.tools = append(mounted.tools, tool.Name)
+		}
+		for _, prompt := range prompts {
+			prompt = mountedPrompt(prefix, backend, prompt)
+			if err := s.RegisterPrompt(prompt); err != nil {
+				return err
+			}
+			mounted.prompts = append(mounted.prompts, prompt.Name)
+		}
+		for _, resource := range resources {
+			if err := s.RegisterResource(resource); err != nil {
+				return err
+			}
+			mounted.resources = append(mounted.resources, resource.URI)
+		}
+		for _, template := range templates {
+			if err := s.RegisterResourceTemplate(template); err != nil {
+				return err
+			}
+			mounted.templates = append(mounted.templates, template.URITemplate)
+		}
+		for key, provider := range completions {
+			if err := s.addCompletion(key, provider); err != nil {
+				return err
+			}
+			mounted.completions = append(mounted.completions, key)
+		}
+		return nil
+	}()
+	if err != nil {
+		s.unmount(mounted)
+		return fmt.Errorf("failed to mount %s: %w", backend.Name, err)
+	}
+
+	backend.gatewaysMu.Lock()
+	backend.gateways = append(backend.gateways, s)
+	backend.gatewaysMu.Unlock()
+	return nil
+}
+
+// isMountedOn reports whether s is mounted on target, directly or through other gateways
+func (s *Server) isMountedOn(target *Server) bool {
+	s.gatewaysMu.Lock()
+	gateways := append([]*Server(nil), s.gateways...)
+	s.gatewaysMu.Unlock()
+	for _, gateway := range gateways {
+		if gateway == target || gateway.isMountedOn(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// mountedParts lists what a mount registered, to roll it back
+type mountedParts struct {
+	tools       []string
+	prompts     []string
+	resources   []string
+	templates   []string
+	completions []completionKey
+}
+
+func (s *Server) unmount(parts mountedParts) {
+	for _, name := range parts.tools {
+		s.UnregisterTool(name)
+	}
+	for _, name := range parts.prompts {
+		s.UnregisterPrompt(name)
+	}
+	for _, uri := range parts.resources {
+		s.UnregisterResource(uri)
+	}
+	for _, uriTemplate := range parts.templates {
+		s.UnregisterResourceTemplate(uriTemplate)
+	}
+	s.completionsMu.Lock()
+	for _, key := range parts.completions {
+		delete(s.completions, key)
+	}
+	s.completionsMu.Unlock()
+}
+
+// mountedName is the name a tool of a server mounted under prefix is exposed as
+func mountedName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + MountSeparator + name
+}
+
+// mountedTool renames tool and routes its calls to backend. Caching and result limits run on the backend,
+// so the gateway copy does not repeat them.
+func mountedTool(prefix string, backend *Server, tool ToolDescription) ToolDescription {
+	name := tool.Name
+	tool.Name = mountedName(prefix, name)
+	tool.Cache = nil
+	tool.mounted = true
+	tool.Handler = func(r *http.Request, params map[string]any) (any, error) {
+		result, _, err := backend.callTool(r, name, params)
+		return result, err
+	}
+	return tool
+}
+
+// mountedPrompt renames prompt and renders it with the definition backend has when the prompt is requested
+func mountedPrompt(prefix string, backend *Server, prompt Prompt) Prompt {
+	name := prompt.Name
+	prompt.Name = mountedName(prefix, name)
+	prompt.Handler = func(r *http.Request, arguments map[string]string) (*PromptResult, error) {
+		current := backend.FindPrompt(name)
+		if current == nil {
+			return nil, &JsonRPCError{Code: ErrInvalidParams, Message: "prompt not found: " + prompt.Name}
+		}
+		return current.Handler(r, arguments)
+	}
+	return prompt
+}
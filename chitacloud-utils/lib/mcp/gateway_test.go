@@ -0,0 +1,166 @@
+package mcp
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGatewayRoutesToMountedServers(t *testing.T) {
+	hour := NewServer("hour", "1.0", "hour")
+	hourCalls := 0
+	hour.RegisterTool(ToolDescription{
+		Name:  "get_time",
+		Cache: &ToolCache{TTL: time.Minute},
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			hourCalls++
+			return map[string]any{"zone": params["zone"]}, nil
+		},
+	})
+	status := "sent"
+	hour.RegisterResource(statusResource(&status))
+	hour.RegisterResourceCompletion("push://deliveries/{id}", "id", CompleteFrom("42"))
+	hour.RegisterPrompt(hourPrompt())
+	hour.RegisterPromptCompletion("plan_meeting", "timezone", CompleteFrom("UTC"))
+
+	examples := NewServer("examples", "1.0", "examples")
+	examples.RegisterTool(namedTool("get_time", "example"))
+
+	gateway := NewServer("gateway", "1.0", "gateway")
+	if err := gateway.Mount("hour", hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Mount("examples", examples); err != nil {
+		t.Fatal(err)
+	}
+
+	tools := gateway.Tools()
+	if len(tools) != 2 || tools[0].Name != "hour.get_time" || tools[1].Name != "examples.get_time" {
+		t.Fatalf("unexpected merged tools %v", tools)
+	}
+	if gateway.FindResource("push://deliveries/42") == nil {
+		t.Error("expected the resource of the mounted server")
+	}
+	if gateway.completionProvider(CompletionReference{Type: RefResource, URI: "push://deliveries/{id}"}, "id") == nil {
+		t.Error("expected the completion of the mounted server")
+	}
+	if gateway.completionProvider(CompletionReference{Type: RefPrompt, Name: "hour.plan_meeting"}, "timezone") == nil {
+		t.Error("expected the prompt completion under the mounted prompt name")
+	}
+	prompt, err := gateway.handleGetPrompt(httptest.NewRequest(http.MethodPost, "/", nil), "hour.plan_meeting", map[string]any{"timezone": "UTC"})
+	if err != nil || prompt.(*PromptResult).Messages[0].Content.Text != "Plan a meeting in UTC" {
+		t.Errorf("expected the prompt of the mounted server, got %v %v", prompt, err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	for range 2 {
+		result, _, err := gateway.callTool(r, "hour.get_time", map[string]any{"zone": "UTC"})
+		if err != nil || result.(map[string]any)["zone"] != "UTC" {
+			t.Fatalf("unexpected result %v %v", result, err)
+		}
+	}
+	if hourCalls != 1 {
+		t.Errorf("expected the backend cache to serve the second call, got %d calls", hourCalls)
+	}
+	if result, _, err := gateway.callTool(r, "examples.get_time", nil); err != nil || result != "example" {
+		t.Errorf("unexpected result %v %v", result, err)
+	}
+}
+
+func TestGatewayKeepsBackendResultLimits(t *testing.T) {
+	backend := NewServer("backend", "1.0", "backend")
+	backend.RegisterTool(ToolDescription{
+		Name:           "report",
+		MaxResultBytes: 1000,
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return map[string]any{"text": strings.Repeat("a", 500)}, nil
+		},
+	})
+	gateway := NewServer("gateway", "1.0", "gateway")
+	gateway.SetLimits(Limits{MaxResultBytes: 100, ResultOverflow: OverflowError})
+	if err := gateway.Mount("backend", backend); err != nil {
+		t.Fatal(err)
+	}
+
+	result, _, err := gateway.callTool(httptest.NewRequest(http.MethodPost, "/", nil), "backend.report", nil)
+	if err != nil || len(result.(map[string]any)["text"].(string)) != 500 {
+		t.Errorf("expected the backend limit to apply instead of the gateway one, got %v", err)
+	}
+}
+
+func TestGatewayReportsConflicts(t *testing.T) {
+	first := NewServer("first", "1.0", "first")
+	first.RegisterTool(namedTool("a", nil))
+	first.RegisterTool(namedTool("b", nil))
+	status := "sent"
+	first.RegisterResource(statusResource(&status))
+
+	first.RegisterResourceTemplate(hourTemplate())
+	first.RegisterResourceCompletion("hour://{timezone}", "timezone", CompleteFrom("UTC"))
+	first.RegisterPrompt(hourPrompt())
+
+	second := NewServer("second", "1.0", "second")
+	second.RegisterTool(namedTool("b", nil))
+	second.RegisterTool(namedTool("c", nil))
+	second.RegisterResource(statusResource(&status))
+	second.RegisterResourceCompletion("hour://{timezone}", "timezone", CompleteFrom("Europe/Madrid"))
+	second.RegisterPrompt(hourPrompt())
+
+	gateway := NewServer("gateway", "1.0", "gateway")
+	gateway.RegisterTool(namedTool("a", nil))
+	if err := gateway.Mount("", first); !errors.Is(err, ErrMountConflict) || !strings.Contains(err.Error(), "tool a") {
+		t.Fatalf("expected a conflict on tool a, got %v", err)
+	}
+	if len(gateway.Tools()) != 1 {
+		t.Errorf("expected nothing mounted after a conflict, got %v", gateway.Tools())
+	}
+
+	if err := gateway.Mount("x", first); err != nil {
+		t.Fatal(err)
+	}
+	err := gateway.Mount("x", second)
+	for _, conflict := range []string{"tool x.b", "prompt x.plan_meeting", "resource push://deliveries/42", "completion of timezone in hour://{timezone}"} {
+		if !errors.Is(err, ErrMountConflict) || !strings.Contains(err.Error(), conflict) {
+			t.Errorf("expected a conflict on %s, got %v", conflict, err)
+		}
+	}
+	if gateway.FindTool("x.c") != nil {
+		t.Error("expected nothing mounted after a conflict")
+	}
+	if values, _ := gateway.completionProvider(CompletionReference{Type: RefResource, URI: "hour://{timezone}"}, "timezone")(nil, CompletionArgument{}, nil); len(values) != 1 || values[0] != "UTC" {
+		t.Errorf("expected the first completion to be kept, got %v", values)
+	}
+
+	if err := first.Mount("gateway", gateway); err == nil {
+		t.Error("expected mount cycles to be rejected")
+	}
+}
+
+func TestGatewayForwardsResourceUpdates(t *testing.T) {
+	status := "queued"
+	backend := NewServer("backend", "1.0", "backend")
+	backend.RegisterResource(statusResource(&status))
+
+	gateway := NewServer("gateway", "1.0", "gateway")
+	gateway.SetStreaming(true)
+	if err := gateway.Mount("push", backend); err != nil {
+		t.Fatal(err)
+	}
+
+	sessionID := initializeSession(t, gateway, nil)
+	stream := openTestStream(t, gateway, sessionID)
+	defer stream.Close()
+	if response := callResourceMethod(t, gateway, sessionID, "resources/subscribe", "push://deliveries/42"); response["error"] != nil {
+		t.Fatalf("subscribe failed: %v", response)
+	}
+
+	backend.PublishResourceUpdated("push://deliveries/42")
+	message := readStreamMessage(t, bufio.NewReader(stream))
+	if message["method"] != "notifications/resources/updated" || message["params"].(map[string]any)["uri"] != "push://deliveries/42" {
+		t.Errorf("unexpected notification %v", message)
+	}
+}
@@ -0,0 +1,66 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/fredyk/westack-go/lambdas"
+)
+
+// ServeHTTP lets the server run as a plain http.Handler, e.g. behind httptest.Server.
+// The JSON-RPC message is decoded from the body and the response is flushed as it is produced.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req MCPRequest
+	reader := io.Reader(r.Body)
+	if s.Limits.MaxRequestBytes > 0 {
+		// One byte past the limit is enough for Handle to reject the request
+		reader = io.LimitReader(r.Body, int64(s.Limits.MaxRequestBytes)+1)
+	}
+	payload, err := io.ReadAll(reader)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	// Oversized payloads are left undecoded for Handle to reject
+	oversized := s.Limits.MaxRequestBytes > 0 && len(payload) > s.Limits.MaxRequestBytes
+	if len(payload) > 0 && !oversized {
+		// REST bodies are tool arguments, they only need to parse as JSON-RPC when they are not
+		if err := json.Unmarshal(payload, &req); err != nil && !s.isRESTRequest(r) {
+			body, _ := FormatMCPServerResponse(0, "", "", nil, nil, &JsonRPCError{Code: ErrParse, Message: err.Error()})
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(body)
+			return
+		}
+	}
+	req.LambdaRequest = lambdas.LambdaRequest{Payload: payload}
+
+	body, err := s.Handle(r, w, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if body == nil {
+		return
+	}
+	defer body.Close()
+
+	flusher, _ := w.(http.Flusher)
+	buffer := make([]byte, 32*1024)
+	for {
+		n, readErr := body.Read(buffer)
+		if n > 0 {
+			if _, err := w.Write(buffer[:n]); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if readErr != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// Package hooks gives the packages under lib/mcp access to server features that are not part of its API
+package hooks
+
+import "net/http"
+
+// OverrideToolHandler swaps the handler of a tool registered on an *mcp.Server without notifying clients or
+// dropping its cache, restore brings back the previous definition. Package mcp sets it.
+var OverrideToolHandler func(server any, name string, handler func(r *http.Request, params map[string]any) (any, error)) (restore func() error, err error)
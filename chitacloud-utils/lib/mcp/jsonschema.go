@@ -0,0 +1,207 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// JSONSchemaDialect is the $schema of the tool schemas sent to clients
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema converts an OpenAPI 3.0 schema to JSON Schema 2020-12. Nullable schemas get a "null" type,
+// boolean exclusive bounds become numeric ones, examples become an array and referenced schemas are
+// collected under $defs. OpenAPI-only keywords such as discriminator, xml and extensions are dropped.
+func JSONSchema(schema *openapi3.Schema) map[string]any {
+	if schema == nil {
+		return nil
+	}
+	converter := &schemaConverter{defs: map[string]any{}}
+	converted := converter.convert(schema)
+	converted["$schema"] = JSONSchemaDialect
+	if len(converter.defs) > 0 {
+		converted["$defs"] = converter.defs
+	}
+	return converted
+}
+
+type schemaConverter struct {
+	defs map[string]any
+}
+
+// convertRef converts a schema reference, moving named schemas to $defs
+func (c *schemaConverter) convertRef(ref *openapi3.SchemaRef) map[string]any {
+	if ref == nil {
+		return map[string]any{}
+	}
+	if ref.Ref == "" {
+		if ref.Value == nil {
+			return map[string]any{}
+		}
+		return c.convert(ref.Value)
+	}
+	if ref.Value == nil {
+		// Unresolved references are kept as they are
+		return map[string]any{"$ref": ref.Ref}
+	}
+
+	name := ref.Ref[strings.LastIndex(ref.Ref, "/")+1:]
+	if _, ok := c.defs[name]; !ok {
+		// Reserve the name first so recursive schemas terminate
+		c.defs[name] = map[string]any{}
+		c.defs[name] = c.convert(ref.Value)
+	}
+	return map[string]any{"$ref": "#/$defs/" + name}
+}
+
+func (c *schemaConverter) convertRefs(refs openapi3.SchemaRefs) []any {
+	converted := make([]any, 0, len(refs))
+	for _, ref := range refs {
+		converted = append(converted, c.convertRef(ref))
+	}
+	return converted
+}
+
+func (c *schemaConverter) convert(schema *openapi3.Schema) map[string]any {
+	out := map[string]any{}
+
+	if schema.Type != nil && len(*schema.Type) > 0 {
+		types := append([]string{}, *schema.Type...)
+		if schema.Nullable && !schema.Type.Includes("null") {
+			types = append(types, "null")
+		}
+		if len(types) == 1 {
+			out["type"] = types[0]
+		} else {
+			out["type"] = types
+		}
+	}
+	if schema.Title != "" {
+		out["title"] = schema.Title
+	}
+	if schema.Description != "" {
+		out["description"] = schema.Description
+	}
+	if schema.Format != "" {
+		out["format"] = schema.Format
+	}
+	if len(schema.Enum) > 0 {
+		enum := append([]any{}, schema.Enum...)
+		if schema.Nullable && !containsNil(enum) {
+			enum = append(enum, nil)
+		}
+		out["enum"] = enum
+	}
+	if schema.Default != nil {
+		out["default"] = schema.Default
+	}
+	if schema.Example != nil {
+		out["examples"] = []any{schema.Example}
+	}
+	if schema.Deprecated {
+		out["deprecated"] = true
+	}
+	if schema.ReadOnly {
+		out["readOnly"] = true
+	}
+	if schema.WriteOnly {
+		out["writeOnly"] = true
+	}
+
+	// Numbers
+	if schema.Min != nil {
+		if schema.ExclusiveMin {
+			out["exclusiveMinimum"] = *schema.Min
+		} else {
+			out["minimum"] = *schema.Min
+		}
+	}
+	if schema.Max != nil {
+		if schema.ExclusiveMax {
+			out["exclusiveMaximum"] = *schema.Max
+		} else {
+			out["maximum"] = *schema.Max
+		}
+	}
+	if schema.MultipleOf != nil {
+		out["multipleOf"] = *schema.MultipleOf
+	}
+
+	// Strings
+	if schema.MinLength > 0 {
+		out["minLength"] = schema.MinLength
+	}
+	if schema.MaxLength != nil {
+		out["maxLength"] = *schema.MaxLength
+	}
+	if schema.Pattern != "" {
+		out["pattern"] = schema.Pattern
+	}
+
+	// Arrays
+	if schema.Items != nil {
+		out["items"] = c.convertRef(schema.Items)
+	}
+	if schema.MinItems > 0 {
+		out["minItems"] = schema.MinItems
+	}
+	if schema.MaxItems != nil {
+		out["maxItems"] = *schema.MaxItems
+	}
+	if schema.UniqueItems {
+		out["uniqueItems"] = true
+	}
+
+	// Objects
+	if len(schema.Properties) > 0 {
+		properties := make(map[string]any, len(schema.Properties))
+		for name, property := range schema.Properties {
+			properties[name] = c.convertRef(property)
+		}
+		out["properties"] = properties
+	}
+	if len(schema.Required) > 0 {
+		out["required"] = append([]string{}, schema.Required...)
+	}
+	if schema.MinProps > 0 {
+		out["minProperties"] = schema.MinProps
+	}
+	if schema.MaxProps != nil {
+		out["maxProperties"] = *schema.MaxProps
+	}
+	if schema.AdditionalProperties.Schema != nil {
+		out["additionalProperties"] = c.convertRef(schema.AdditionalProperties.Schema)
+	} else if schema.AdditionalProperties.Has != nil {
+		out["additionalProperties"] = *schema.AdditionalProperties.Has
+	}
+
+	// Composition
+	if len(schema.AllOf) > 0 {
+		out["allOf"] = c.convertRefs(schema.AllOf)
+	}
+	if len(schema.AnyOf) > 0 {
+		out["anyOf"] = c.convertRefs(schema.AnyOf)
+	}
+	if len(schema.OneOf) > 0 {
+		out["oneOf"] = c.convertRefs(schema.OneOf)
+	}
+	if schema.Not != nil {
+		out["not"] = c.convertRef(schema.Not)
+	}
+
+	// Nullable schemas without a type can only accept null through a union
+	if schema.Nullable && (schema.Type == nil || len(*schema.Type) == 0) && len(out) > 0 {
+		return map[string]any{"anyOf": []any{out, map[string]any{"type": "null"}}}
+	}
+	return out
+}
+
+func containsNil(values []any) bool {
+	for _, value := range values {
+		if value == nil {
+			return true
+		}
+	}
+	return false
+}
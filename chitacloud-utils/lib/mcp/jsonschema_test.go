@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestJSONSchemaConvertsOpenAPIKeywords(t *testing.T) {
+	minimum, maximum := 0.0, 10.0
+	address := &openapi3.SchemaRef{
+		Ref: "#/components/schemas/Address",
+		Value: &openapi3.Schema{
+			Type:       &openapi3.Types{openapi3.TypeObject},
+			Properties: openapi3.Schemas{"city": {Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}}}},
+		},
+	}
+	schema := &openapi3.Schema{
+		Type: &openapi3.Types{openapi3.TypeObject},
+		Properties: openapi3.Schemas{
+			"nickname": {Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, Nullable: true, Example: "bob"}},
+			"level":    {Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, Enum: []any{"low", "high"}, Nullable: true}},
+			"score":    {Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeNumber}, Min: &minimum, ExclusiveMin: true, Max: &maximum}},
+			"home":     address,
+			"work":     address,
+		},
+		Required: []string{"score"},
+	}
+
+	converted := JSONSchema(schema)
+	encoded, err := json.Marshal(converted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual map[string]any
+	json.Unmarshal(encoded, &actual)
+
+	expected := map[string]any{
+		"$schema": JSONSchemaDialect,
+		"type":    "object",
+		"properties": map[string]any{
+			"nickname": map[string]any{"type": []any{"string", "null"}, "examples": []any{"bob"}},
+			"level":    map[string]any{"type": []any{"string", "null"}, "enum": []any{"low", "high", nil}},
+			"score":    map[string]any{"type": "number", "exclusiveMinimum": float64(0), "maximum": float64(10)},
+			"home":     map[string]any{"$ref": "#/$defs/Address"},
+			"work":     map[string]any{"$ref": "#/$defs/Address"},
+		},
+		"required": []any{"score"},
+		"$defs": map[string]any{
+			"Address": map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected schema\nexpected: %v\nactual:   %v", expected, actual)
+	}
+}
+
+func TestJSONSchemaHandlesRecursiveRefs(t *testing.T) {
+	node := &openapi3.SchemaRef{Ref: "#/components/schemas/Node", Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeObject}}}
+	node.Value.Properties = openapi3.Schemas{"children": {Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeArray}, Items: node}}}
+
+	converted := JSONSchema(node.Value)
+	defs := converted["$defs"].(map[string]any)
+	if _, ok := defs["Node"]; !ok {
+		t.Fatalf("expected Node in $defs, got %v", converted)
+	}
+	items := converted["properties"].(map[string]any)["children"].(map[string]any)["items"]
+	if !reflect.DeepEqual(items, map[string]any{"$ref": "#/$defs/Node"}) {
+		t.Errorf("unexpected items %v", items)
+	}
+}
+
+func TestJSONSchemaWrapsUntypedNullable(t *testing.T) {
+	schema := &openapi3.Schema{Nullable: true, Description: "anything"}
+	converted := JSONSchema(schema)
+	if len(converted["anyOf"].([]any)) != 2 || converted["$schema"] != JSONSchemaDialect {
+		t.Errorf("unexpected schema %v", converted)
+	}
+}
+
+func TestToolsListEmitsJSONSchema(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.RegisterTool(ToolDescription{
+		Name: "greet",
+		InputSchema: &openapi3.Schema{
+			Type:       &openapi3.Types{openapi3.TypeObject},
+			Properties: openapi3.Schemas{"name": {Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, Nullable: true}}},
+		},
+		Handler: func(r *http.Request, params map[string]any) (any, error) { return nil, nil },
+	})
+	server.RegisterTool(ToolDescription{
+		Name:    "noop",
+		Handler: func(r *http.Request, params map[string]any) (any, error) { return nil, nil },
+	})
+
+	encoded, err := json.Marshal(server.HandleTools())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Tools []map[string]any `json:"tools"`
+	}
+	json.Unmarshal(encoded, &result)
+
+	greet := result.Tools[0]["inputSchema"].(map[string]any)
+	if greet["$schema"] != JSONSchemaDialect {
+		t.Errorf("expected the dialect, got %v", greet)
+	}
+	name := greet["properties"].(map[string]any)["name"].(map[string]any)
+	if name["nullable"] != nil || !reflect.DeepEqual(name["type"], []any{"string", "null"}) {
+		t.Errorf("unexpected name property %v", name)
+	}
+	if _, ok := result.Tools[0]["outputSchema"]; ok {
+		t.Error("expected no outputSchema")
+	}
+	if noop := result.Tools[1]["inputSchema"].(map[string]any); noop["type"] != "object" {
+		t.Errorf("unexpected default input schema %v", noop)
+	}
+}
@@ -0,0 +1,204 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+)
+
+// OverflowPolicy decides what happens to a tool result larger than the result size limit
+type OverflowPolicy int
+
+const (
+	// OverflowDefault uses the policy of the server limits, OverflowTruncate when unset
+	OverflowDefault OverflowPolicy = iota
+	// OverflowTruncate cuts the text content of the result and appends a truncation marker. Tools with an
+	// OutputSchema always use OverflowError, their structuredContent cannot be truncated.
+	OverflowTruncate
+	// OverflowError fails the call with ErrInternal
+	OverflowError
+)
+
+// Limits bounds what the server decodes and returns, zero values disable each limit
+type Limits struct {
+	// MaxRequestBytes rejects request bodies above this size with 413. ServeHTTP stops reading the body past the
+	// limit, but a lambda runtime has already read and decoded the payload when Handle runs, so there it only
+	// bounds the work done after decoding. Bound the body size in front of lambdas as well.
+	MaxRequestBytes int
+	// MaxArgumentDepth rejects tool arguments nested deeper than this number of objects and arrays
+	MaxArgumentDepth int
+	// MaxResultBytes is the largest JSON encoded tool result, tools may override it
+	MaxResultBytes int
+	// ResultOverflow handles results above MaxResultBytes, tools may override it
+	ResultOverflow OverflowPolicy
+}
+
+// SetLimits replaces the request and result limits of the server
+func (s *Server) SetLimits(limits Limits) {
+	s.Limits = limits
+}
+
+// checkRequestSize rejects payloads above MaxRequestBytes, once they have been read and decoded
+func (s *Server) checkRequestSize(r *http.Request, req MCPRequest) error {
+	limit := s.Limits.MaxRequestBytes
+	if limit <= 0 {
+		return nil
+	}
+	size := max(int64(len(req.LambdaRequest.Payload)), r.ContentLength)
+	if size > int64(limit) {
+		return &JsonRPCError{Code: ErrInvalidRequest, Message: fmt.Sprintf("request of %d bytes exceeds the limit of %d bytes", size, limit), Data: map[string]any{"limit": limit}}
+	}
+	return nil
+}
+
+// checkArguments rejects arguments nested deeper than MaxArgumentDepth
+func (s *Server) checkArguments(arguments map[string]any) error {
+	limit := s.Limits.MaxArgumentDepth
+	if limit <= 0 {
+		return nil
+	}
+	if depth := nestingDepth(arguments); depth > limit {
+		return &JsonRPCError{Code: ErrInvalidParams, Message: fmt.Sprintf("arguments nested %d levels deep exceed the limit of %d", depth, limit), Data: map[string]any{"limit": limit}}
+	}
+	return nil
+}
+
+// nestingDepth counts the objects and arrays enclosing the deepest value, the arguments object counts as one
+func nestingDepth(value any) int {
+	deepest := 0
+	switch typed := value.(type) {
+	case map[string]any:
+		for _, item := range typed {
+			deepest = max(deepest, nestingDepth(item))
+		}
+	case []any:
+		for _, item := range typed {
+			deepest = max(deepest, nestingDepth(item))
+		}
+	default:
+		return 0
+	}
+	return deepest + 1
+}
+
+// limitResult applies the result size limit of tool, truncating or failing oversized results. Mounted tools
+// keep the result of their backend.
+func (s *Server) limitResult(tool *ToolDescription, result any) (any, error) {
+	if tool.mounted {
+		return result, nil
+	}
+	limit := tool.MaxResultBytes
+	if limit <= 0 {
+		limit = s.Limits.MaxResultBytes
+	}
+	if limit <= 0 || result == nil {
+		return result, nil
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil || len(encoded) <= limit {
+		// Results that do not encode fail later with a proper error
+		return result, nil
+	}
+
+	policy := tool.ResultOverflow
+	if policy == OverflowDefault {
+		policy = s.Limits.ResultOverflow
+	}
+	if policy == OverflowError || tool.OutputSchema != nil {
+		return nil, &JsonRPCError{
+			Code:    ErrInternal,
+			Message: fmt.Sprintf("result of tool %s is %d bytes, above the limit of %d bytes", tool.Name, len(encoded), limit),
+			Data:    map[string]any{"size": len(encoded), "limit": limit},
+		}
+	}
+	return truncateResult(result, encoded, limit), nil
+}
+
+// truncationMarker is appended to truncated text content
+func truncationMarker(size, limit int) string {
+	return fmt.Sprintf("\n[truncated: result was %d bytes, the limit is %d bytes]", size, limit)
+}
+
+// truncateResult cuts the text content of a result to fit roughly in limit bytes. CallToolResults keep their
+// text blocks up to the budget, any other result is returned as a CallToolResult holding its truncated JSON.
+func truncateResult(result any, encoded []byte, limit int) any {
+	marker := truncationMarker(len(encoded), limit)
+
+	if text, ok := result.(string); ok {
+		return truncateText(text, limit-len(marker)) + marker
+	}
+
+	blocks, ok := callToolResultBlocks(result)
+	if !ok {
+		return map[string]any{
+			"content": []any{map[string]any{"type": "text", "text": truncateText(string(encoded), limit-len(marker)) + marker}},
+		}
+	}
+
+	textBytes := 0
+	for _, block := range blocks {
+		if text, ok := block["text"].(string); ok {
+			textBytes += len(text)
+		}
+	}
+	budget := limit - (len(encoded) - textBytes) - len(marker)
+
+	truncated := make([]any, 0, len(blocks))
+	for _, block := range blocks {
+		text, isText := block["text"].(string)
+		if !isText {
+			truncated = append(truncated, block)
+			continue
+		}
+		copied := make(map[string]any, len(block))
+		for key, value := range block {
+			copied[key] = value
+		}
+		if len(text) > budget {
+			copied["text"] = truncateText(text, budget) + marker
+			truncated = append(truncated, copied)
+			break
+		}
+		budget -= len(text)
+		truncated = append(truncated, copied)
+	}
+
+	// structuredContent would no longer match the text, so it is dropped
+	wrapped := map[string]any{"content": truncated}
+	if isError, ok := result.(map[string]any)["isError"]; ok {
+		wrapped["isError"] = isError
+	}
+	return wrapped
+}
+
+// callToolResultBlocks returns the content blocks of a CallToolResult
+func callToolResultBlocks(result any) ([]map[string]any, bool) {
+	if !isCallToolResult(result) {
+		return nil, false
+	}
+	encoded, err := json.Marshal(result.(map[string]any)["content"])
+	if err != nil {
+		return nil, false
+	}
+	var blocks []map[string]any
+	if err := json.Unmarshal(encoded, &blocks); err != nil {
+		return nil, false
+	}
+	return blocks, true
+}
+
+// truncateText cuts text to at most n bytes without splitting a UTF-8 sequence
+func truncateText(text string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func limitsTestServer(limits Limits) *Server {
+	server := NewServer("test", "1.0", "test")
+	server.SetLimits(limits)
+	server.RegisterTool(ToolDescription{
+		Name: "echo",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return params, nil
+		},
+	})
+	server.RegisterTool(ToolDescription{
+		Name: "report",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return map[string]any{"content": []any{
+				map[string]any{"type": "text", "text": "summary"},
+				map[string]any{"type": "text", "text": strings.Repeat("é", 200)},
+				map[string]any{"type": "text", "text": "appendix"},
+			}}, nil
+		},
+	})
+	server.RegisterTool(ToolDescription{
+		Name:           "strict",
+		MaxResultBytes: 20,
+		ResultOverflow: OverflowError,
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return map[string]any{"text": strings.Repeat("x", 50)}, nil
+		},
+	})
+	return server
+}
+
+func TestOversizedRequestsAreRejected(t *testing.T) {
+	server := limitsTestServer(Limits{MaxRequestBytes: 64})
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"text":"` + strings.Repeat("a", 100) + `"}}}`
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d %q", w.Code, w.Body.String())
+	}
+	var response struct {
+		Error JsonRPCError `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Error.Code != ErrInvalidRequest {
+		t.Errorf("expected an invalid request error, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected small requests to pass, got %d", w.Code)
+	}
+}
+
+func TestDeepArgumentsAreRejected(t *testing.T) {
+	server := limitsTestServer(Limits{MaxArgumentDepth: 3})
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	if _, _, err := server.callTool(r, "echo", map[string]any{"a": map[string]any{"b": []any{1}}}); err != nil {
+		t.Errorf("expected three levels to pass, got %v", err)
+	}
+	_, _, err := server.callTool(r, "echo", map[string]any{"a": map[string]any{"b": []any{[]any{1}}}})
+	if rpcError(err, nil).Code != ErrInvalidParams {
+		t.Errorf("expected four levels to be rejected, got %v", err)
+	}
+}
+
+func TestOversizedResults(t *testing.T) {
+	server := limitsTestServer(Limits{MaxResultBytes: 200})
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	result, _, err := server.callTool(r, "echo", map[string]any{"text": strings.Repeat("a", 500)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := result.(map[string]any)["content"].([]any)[0].(map[string]any)["text"].(string)
+	if !strings.HasSuffix(text, "the limit is 200 bytes]") || len(text) > 200 {
+		t.Errorf("expected truncated JSON text with a marker, got %q", text)
+	}
+
+	result, _, err = server.callTool(r, "report", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := result.(map[string]any)["content"].([]any)
+	if len(blocks) != 2 || blocks[0].(map[string]any)["text"] != "summary" {
+		t.Fatalf("expected the blocks after the cut to be dropped, got %v", blocks)
+	}
+	if cut := blocks[1].(map[string]any)["text"].(string); !utf8.ValidString(cut) || !strings.Contains(cut, "[truncated:") {
+		t.Errorf("expected valid UTF-8 with a marker, got %q", cut)
+	}
+
+	_, _, err = server.callTool(r, "strict", nil)
+	if rpcErr := rpcError(err, nil); rpcErr.Code != ErrInternal || !strings.Contains(rpcErr.Message, "above the limit of 20 bytes") {
+		t.Errorf("expected the per-tool limit to fail the call, got %v", err)
+	}
+
+	if result, _, err := server.callTool(r, "echo", map[string]any{"text": "short"}); err != nil || result.(map[string]any)["text"] != "short" {
+		t.Errorf("expected small results to pass untouched, got %v %v", result, err)
+	}
+}
+
+func TestOversizedStructuredResultsFail(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetLimits(Limits{MaxResultBytes: 50, ResultOverflow: OverflowTruncate})
+	server.RegisterTool(ToolDescription{
+		Name:         "structured",
+		OutputSchema: openapi3.NewObjectSchema().WithProperty("text", openapi3.NewStringSchema()),
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return map[string]any{"text": strings.Repeat("a", 100)}, nil
+		},
+	})
+
+	_, _, err := server.callTool(httptest.NewRequest(http.MethodPost, "/", nil), "structured", nil)
+	if rpcErr := rpcError(err, nil); rpcErr.Code != ErrInternal || !strings.Contains(rpcErr.Message, "above the limit of 50 bytes") {
+		t.Errorf("expected oversized structured results to fail instead of losing structuredContent, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+// defaultSensitiveHeaders are never logged as-is
+var defaultSensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// defaultSensitiveArguments are redacted from logged tool arguments, matched case-insensitively
+var defaultSensitiveArguments = []string{"password", "secret", "token", "apiKey", "accessToken", "authorization"}
+
+type loggerContextKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying logger
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// LoggerFromContext returns the request-scoped logger set by Server.Handle, or slog.Default
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// SetLogger replaces the server logger, nil restores the default stdout logger
+func (s *Server) SetLogger(logger *slog.Logger) {
+	s.Logger = logger
+}
+
+// SetSensitiveArguments configures extra argument names whose values are redacted from logs
+func (s *Server) SetSensitiveArguments(names ...string) {
+	s.SensitiveArguments = names
+}
+
+// logger returns the configured logger or a text logger on stdout honoring the debug flag
+func (s *Server) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	level := slog.LevelInfo
+	if s.Debug {
+		level = slog.LevelDebug
+	}
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+}
+
+// requestLogger returns a logger carrying the attributes that identify req
+func (s *Server) requestLogger(r *http.Request, req MCPRequest) *slog.Logger {
+	attrs := []any{slog.String("method", req.Method), slog.Int("requestId", req.ID)}
+	if sessionID := r.Header.Get(SessionHeader); sessionID != "" {
+		attrs = append(attrs, slog.String("session", sessionID))
+	}
+	if req.Method == "tools/call" && req.Params.Name != "" {
+		attrs = append(attrs, slog.String("tool", req.Params.Name))
+	}
+	return s.logger().With(attrs...)
+}
+
+// RedactHeaders returns a copy of headers with credentials replaced by a marker
+func RedactHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	for _, name := range defaultSensitiveHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, redactedValue)
+		}
+	}
+	return redacted
+}
+
+// RedactArguments returns a deep copy of args with the values of sensitive keys replaced by a marker
+func (s *Server) RedactArguments(args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+	return redactValue(args, s.isSensitiveArgument).(map[string]any)
+}
+
+func (s *Server) isSensitiveArgument(name string) bool {
+	for _, sensitive := range defaultSensitiveArguments {
+		if strings.EqualFold(name, sensitive) {
+			return true
+		}
+	}
+	for _, sensitive := range s.SensitiveArguments {
+		if strings.EqualFold(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactValue(value any, sensitive func(string) bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			if sensitive(key) {
+				out[key] = redactedValue
+			} else {
+				out[key] = redactValue(item, sensitive)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = redactValue(item, sensitive)
+		}
+		return out
+	default:
+		return value
+	}
+}
@@ -0,0 +1,80 @@
+package mcp
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedactArguments(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetSensitiveArguments("to")
+
+	redacted := server.RedactArguments(map[string]any{
+		"to":    "ExpoPushToken[abc]",
+		"title": "Hello",
+		"data":  map[string]any{"Password": "hunter2", "nested": []any{map[string]any{"apiKey": "k"}}},
+	})
+
+	if redacted["to"] != redactedValue {
+		t.Errorf("expected configured argument to be redacted, got %v", redacted["to"])
+	}
+	if redacted["title"] != "Hello" {
+		t.Errorf("expected title to be kept, got %v", redacted["title"])
+	}
+	data := redacted["data"].(map[string]any)
+	if data["Password"] != redactedValue {
+		t.Errorf("expected nested password to be redacted, got %v", data["Password"])
+	}
+	if nested := data["nested"].([]any)[0].(map[string]any); nested["apiKey"] != redactedValue {
+		t.Errorf("expected apiKey inside slice to be redacted, got %v", nested["apiKey"])
+	}
+}
+
+func TestHandleLogsRequestAttributesWithoutSecrets(t *testing.T) {
+	var logs bytes.Buffer
+	server := NewServer("test", "1.0", "test")
+	server.SetLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	var handlerLogger *slog.Logger
+	server.RegisterTool(ToolDescription{
+		Name: "login",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			handlerLogger = LoggerFromContext(r.Context())
+			handlerLogger.Info("inside handler")
+			return map[string]any{"ok": true}, nil
+		},
+	})
+
+	sessionID := initializeSession(t, server, nil)
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Authorization", "Bearer super-secret-token")
+	r.Header.Set("Mcp-Session-Id", sessionID)
+	_, err := server.Handle(r, httptest.NewRecorder(), MCPRequest{
+		JSONRPC: "2.0",
+		ID:      3,
+		Method:  "tools/call",
+		Params:  MCPRequestParams{Name: "login", Arguments: map[string]any{"password": "hunter2"}},
+	})
+	if err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+
+	output := logs.String()
+	for _, secret := range []string{"super-secret-token", "hunter2"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("logs should not contain %q:\n%s", secret, output)
+		}
+	}
+	for _, attr := range []string{"method=tools/call", "requestId=3", "session=" + sessionID, "tool=login", "msg=\"inside handler\""} {
+		if !strings.Contains(output, attr) {
+			t.Errorf("logs should contain %q:\n%s", attr, output)
+		}
+	}
+	if handlerLogger == nil {
+		t.Error("handler should receive the request logger")
+	}
+}
@@ -0,0 +1,418 @@
+// Package mcptest provides an in-memory harness for testing MCP servers without a lambda runtime
+package mcptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp/client"
+)
+
+// ConformanceOption configures the conformance suite
+type ConformanceOption func(*conformance)
+
+// WithToolCall makes the suite call a tool and check that it returns a valid CallToolResult.
+// Tools are never called unless listed, as they may have side effects.
+func WithToolCall(name string, arguments map[string]any) ConformanceOption {
+	return func(c *conformance) {
+		c.toolCalls = append(c.toolCalls, toolCall{name: name, arguments: arguments})
+	}
+}
+
+// WithOrigin sets the browser origin used to check CORS preflight requests
+func WithOrigin(origin string) ConformanceOption {
+	return func(c *conformance) {
+		c.origin = origin
+	}
+}
+
+type toolCall struct {
+	name      string
+	arguments map[string]any
+}
+
+type conformance struct {
+	url       string
+	origin    string
+	toolCalls []toolCall
+	sessionID string
+}
+
+// Conformance checks that server follows the MCP Streamable HTTP specification, serving it over HTTP
+func Conformance(t *testing.T, server *mcp.Server, opts ...ConformanceOption) {
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	ConformanceURL(t, httpServer.URL, opts...)
+}
+
+// ConformanceURL checks that the MCP endpoint at url follows the specification. Each check runs as a subtest.
+func ConformanceURL(t *testing.T, url string, opts ...ConformanceOption) {
+	c := &conformance{url: url, origin: "http://localhost:6274"}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	t.Run("initialize", c.checkInitialize)
+	t.Run("version negotiation", c.checkVersionNegotiation)
+	t.Run("notifications", c.checkNotifications)
+	t.Run("id echoing", c.checkIDEchoing)
+	t.Run("error codes", c.checkErrorCodes)
+	t.Run("tools/list", c.checkToolsList)
+	for _, call := range c.toolCalls {
+		call := call
+		t.Run("tools/call "+call.name, func(t *testing.T) { c.checkToolCall(t, call) })
+	}
+	t.Run("CORS preflight", c.checkPreflight)
+}
+
+// exchange is a raw HTTP round trip with the endpoint
+type exchange struct {
+	status   int
+	header   http.Header
+	body     []byte
+	messages []client.Message
+}
+
+// response returns the response message answering id
+func (e exchange) response(t *testing.T, id int) client.Message {
+	t.Helper()
+	for _, message := range e.messages {
+		if message.IsResponse() && string(message.ID) == strings.TrimSpace(string(mustJSON(id))) {
+			if message.JSONRPC != "2.0" {
+				t.Errorf("response has jsonrpc %q, expected 2.0", message.JSONRPC)
+			}
+			return message
+		}
+	}
+	t.Fatalf("no response with id %d in %q", id, e.body)
+	return client.Message{}
+}
+
+func (c *conformance) send(t *testing.T, method string, payload []byte, header http.Header) exchange {
+	t.Helper()
+	req, err := http.NewRequest(method, c.url, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if c.sessionID != "" {
+		req.Header.Set(mcp.SessionHeader, c.sessionID)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s request failed: %v", method, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	result := exchange{status: resp.StatusCode, header: resp.Header, body: body}
+	if len(bytes.TrimSpace(body)) > 0 && method != http.MethodOptions {
+		contentType := resp.Header.Get("Content-Type")
+		if strings.HasPrefix(contentType, "text/event-stream") {
+			checkFraming(t, body)
+		}
+		if result.messages, err = client.ParseMessages(contentType, bytes.NewReader(body)); err != nil {
+			t.Fatalf("invalid response body %q: %v", body, err)
+		}
+	}
+	return result
+}
+
+// call posts a JSON-RPC request and returns the exchange
+func (c *conformance) call(t *testing.T, id int, method string, params any) exchange {
+	t.Helper()
+	request := map[string]any{"jsonrpc": "2.0", "id": id, "method": method}
+	if params != nil {
+		request["params"] = params
+	}
+	return c.send(t, http.MethodPost, mustJSON(request), nil)
+}
+
+// checkFraming verifies that an SSE body only holds complete events made of known fields
+func checkFraming(t *testing.T, body []byte) {
+	t.Helper()
+	if !bytes.HasSuffix(body, []byte("\n\n")) {
+		t.Errorf("SSE body does not end with a blank line: %q", body)
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(body), "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		field, _, _ := strings.Cut(line, ":")
+		switch {
+		case line == "", strings.HasPrefix(line, ":"):
+		case field == "data", field == "event", field == "id", field == "retry":
+		default:
+			t.Errorf("unexpected SSE line %q", line)
+		}
+	}
+}
+
+func (c *conformance) initialize(t *testing.T, version string) map[string]any {
+	t.Helper()
+	exchange := c.call(t, 1, "initialize", map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "mcptest-conformance", "version": "1.0.0"},
+	})
+	if exchange.status != http.StatusOK {
+		t.Fatalf("initialize answered HTTP %d: %s", exchange.status, exchange.body)
+	}
+	response := exchange.response(t, 1)
+	if response.Error != nil {
+		t.Fatalf("initialize failed: %s", response.Error.Message)
+	}
+	if sessionID := exchange.header.Get(mcp.SessionHeader); sessionID != "" {
+		c.sessionID = sessionID
+	}
+	var result map[string]any
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		t.Fatalf("initialize result is not an object: %s", response.Result)
+	}
+	return result
+}
+
+func (c *conformance) checkInitialize(t *testing.T) {
+	result := c.initialize(t, mcp.LatestProtocolVersion)
+	if result["protocolVersion"] != mcp.LatestProtocolVersion {
+		t.Errorf("expected protocolVersion %s to be accepted, got %v", mcp.LatestProtocolVersion, result["protocolVersion"])
+	}
+	if _, ok := result["capabilities"].(map[string]any); !ok {
+		t.Errorf("capabilities must be an object, got %v", result["capabilities"])
+	}
+	serverInfo, ok := result["serverInfo"].(map[string]any)
+	if !ok {
+		t.Fatalf("serverInfo must be an object, got %v", result["serverInfo"])
+	}
+	if name, _ := serverInfo["name"].(string); name == "" {
+		t.Error("serverInfo.name is required")
+	}
+	if version, _ := serverInfo["version"].(string); version == "" {
+		t.Error("serverInfo.version is required")
+	}
+}
+
+func (c *conformance) checkVersionNegotiation(t *testing.T) {
+	sessionID := c.sessionID
+	defer func() { c.sessionID = sessionID }()
+	c.sessionID = ""
+
+	result := c.initialize(t, "1999-01-01")
+	version, _ := result["protocolVersion"].(string)
+	if version == "" || version == "1999-01-01" {
+		t.Errorf("an unsupported version must be answered with a supported one, got %q", version)
+	}
+}
+
+func (c *conformance) checkNotifications(t *testing.T) {
+	notification := mustJSON(map[string]any{"jsonrpc": "2.0", "method": "notifications/initialized"})
+	exchange := c.send(t, http.MethodPost, notification, nil)
+	if exchange.status != http.StatusAccepted {
+		t.Errorf("notifications must be acknowledged with 202, got %d", exchange.status)
+	}
+	if len(exchange.messages) > 0 {
+		t.Errorf("notifications must not get a response, got %q", exchange.body)
+	}
+}
+
+func (c *conformance) checkIDEchoing(t *testing.T) {
+	response := c.call(t, 4242, "ping", nil).response(t, 4242)
+	if response.Error != nil {
+		t.Fatalf("ping failed: %s", response.Error.Message)
+	}
+	var result map[string]any
+	if err := json.Unmarshal(response.Result, &result); err != nil || len(result) != 0 {
+		t.Errorf("ping must return an empty object, got %s", response.Result)
+	}
+}
+
+func (c *conformance) checkErrorCodes(t *testing.T) {
+	expectError := func(t *testing.T, exchange exchange, id int, codes ...int) {
+		t.Helper()
+		response := exchange.response(t, id)
+		if response.Error == nil {
+			t.Fatalf("expected an error, got %s", response.Result)
+		}
+		for _, code := range codes {
+			if response.Error.Code == code {
+				return
+			}
+		}
+		t.Errorf("expected error code %v, got %d: %s", codes, response.Error.Code, response.Error.Message)
+	}
+
+	t.Run("unknown method", func(t *testing.T) {
+		expectError(t, c.call(t, 11, "conformance/unknown", nil), 11, mcp.ErrMethodNotFound)
+	})
+	t.Run("unknown tool", func(t *testing.T) {
+		exchange := c.call(t, 12, "tools/call", map[string]any{"name": "conformance-missing-tool", "arguments": map[string]any{}})
+		response := exchange.response(t, 12)
+		if response.Error == nil {
+			// Tool failures may also be reported as results with isError set
+			var result struct {
+				IsError bool `json:"isError"`
+			}
+			if json.Unmarshal(response.Result, &result); !result.IsError {
+				t.Errorf("calling an unknown tool must fail, got %s", response.Result)
+			}
+			return
+		}
+		expectError(t, exchange, 12, mcp.ErrInvalidParams)
+	})
+	t.Run("parse error", func(t *testing.T) {
+		exchange := c.send(t, http.MethodPost, []byte(`{"jsonrpc": "2.0", "id": 13, "method": `), nil)
+		if exchange.status == http.StatusBadRequest && len(exchange.messages) == 0 {
+			return
+		}
+		for _, message := range exchange.messages {
+			if message.Error != nil && message.Error.Code == mcp.ErrParse {
+				return
+			}
+		}
+		t.Errorf("malformed JSON must be rejected with %d, got HTTP %d: %s", mcp.ErrParse, exchange.status, exchange.body)
+	})
+}
+
+func (c *conformance) checkToolsList(t *testing.T) {
+	response := c.call(t, 21, "tools/list", nil).response(t, 21)
+	if response.Error != nil {
+		t.Fatalf("tools/list failed: %s", response.Error.Message)
+	}
+	var result struct {
+		Tools []map[string]any `json:"tools"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil || result.Tools == nil {
+		t.Fatalf("tools/list must return a tools array, got %s", response.Result)
+	}
+
+	seen := map[string]bool{}
+	for _, tool := range result.Tools {
+		name, _ := tool["name"].(string)
+		if name == "" {
+			t.Errorf("tool without a name: %v", tool)
+			continue
+		}
+		if seen[name] {
+			t.Errorf("duplicate tool %s", name)
+		}
+		seen[name] = true
+
+		if description, ok := tool["description"]; ok {
+			if _, isString := description.(string); !isString {
+				t.Errorf("%s: description must be a string", name)
+			}
+		}
+		checkObjectSchema(t, name, "inputSchema", tool["inputSchema"], true)
+		checkObjectSchema(t, name, "outputSchema", tool["outputSchema"], false)
+	}
+}
+
+func checkObjectSchema(t *testing.T, tool, field string, value any, required bool) {
+	t.Helper()
+	if value == nil {
+		if required {
+			t.Errorf("%s: %s is required", tool, field)
+		}
+		return
+	}
+	schema, ok := value.(map[string]any)
+	if !ok {
+		t.Errorf("%s: %s must be an object, got %v", tool, field, value)
+		return
+	}
+	if schema["type"] != "object" {
+		t.Errorf("%s: %s must describe an object, got type %v", tool, field, schema["type"])
+	}
+}
+
+func (c *conformance) checkToolCall(t *testing.T, call toolCall) {
+	arguments := call.arguments
+	if arguments == nil {
+		arguments = map[string]any{}
+	}
+	response := c.call(t, 31, "tools/call", map[string]any{"name": call.name, "arguments": arguments}).response(t, 31)
+	if response.Error != nil {
+		t.Fatalf("tools/call %s failed: %d %s", call.name, response.Error.Code, response.Error.Message)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		t.Fatalf("CallToolResult must be an object, got %s", response.Result)
+	}
+	content, ok := result["content"].([]any)
+	if !ok {
+		t.Fatalf("CallToolResult.content must be an array, got %v", result["content"])
+	}
+	for i, item := range content {
+		block, ok := item.(map[string]any)
+		if !ok {
+			t.Errorf("content[%d] must be an object", i)
+			continue
+		}
+		switch block["type"] {
+		case "text":
+			if _, ok := block["text"].(string); !ok {
+				t.Errorf("content[%d]: text blocks need a text string", i)
+			}
+		case "image", "audio":
+			if _, ok := block["data"].(string); !ok {
+				t.Errorf("content[%d]: %s blocks need base64 data", i, block["type"])
+			}
+			if _, ok := block["mimeType"].(string); !ok {
+				t.Errorf("content[%d]: %s blocks need a mimeType", i, block["type"])
+			}
+		case "resource", "resource_link":
+		default:
+			t.Errorf("content[%d]: unknown content type %v", i, block["type"])
+		}
+	}
+	if isError, ok := result["isError"]; ok {
+		if _, isBool := isError.(bool); !isBool {
+			t.Errorf("isError must be a boolean, got %v", isError)
+		}
+	}
+	if structured, ok := result["structuredContent"]; ok {
+		if _, isObject := structured.(map[string]any); !isObject {
+			t.Errorf("structuredContent must be an object, got %v", structured)
+		}
+	}
+}
+
+func (c *conformance) checkPreflight(t *testing.T) {
+	header := http.Header{
+		"Origin":                         {c.origin},
+		"Access-Control-Request-Method":  {"POST"},
+		"Access-Control-Request-Headers": {"content-type, " + strings.ToLower(mcp.SessionHeader)},
+	}
+	exchange := c.send(t, http.MethodOptions, nil, header)
+	if exchange.status < 200 || exchange.status >= 300 {
+		t.Fatalf("preflight must succeed, got HTTP %d", exchange.status)
+	}
+	if exchange.header.Get("Access-Control-Allow-Origin") == "" {
+		// The origin is not allowed, which is a valid policy
+		return
+	}
+	if methods := strings.ToUpper(exchange.header.Get("Access-Control-Allow-Methods")); !strings.Contains(methods, "POST") {
+		t.Errorf("preflight must allow POST, got %q", methods)
+	}
+	if headers := strings.ToLower(exchange.header.Get("Access-Control-Allow-Headers")); headers != "*" && !strings.Contains(headers, strings.ToLower(mcp.SessionHeader)) {
+		t.Errorf("preflight must allow the %s header, got %q", mcp.SessionHeader, headers)
+	}
+}
+
+func mustJSON(value any) []byte {
+	payload, _ := json.Marshal(value)
+	return payload
+}
@@ -0,0 +1,164 @@
+// Package mcptest provides an in-memory harness for testing MCP servers without a lambda runtime
+package mcptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp/client"
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp/internal/hooks"
+	"github.com/fredyk/westack-go/lambdas"
+)
+
+// Harness sends requests to a server through Server.Handle, the way the lambda runtime does
+type Harness struct {
+	T      testing.TB
+	Server *mcp.Server
+	// Header holds extra headers sent with every request
+	Header http.Header
+	// SessionID is sent with every request, it is set by Initialize
+	SessionID string
+	// OnRequest answers server-to-client requests, such as sampling/createMessage, received in responses
+	OnRequest func(method string, params json.RawMessage) (any, error)
+
+	nextID int
+}
+
+// New creates a harness for server
+func New(t testing.TB, server *mcp.Server) *Harness {
+	return &Harness{T: t, Server: server, Header: http.Header{}}
+}
+
+// NewRequest builds the MCPRequest the lambda runtime would decode for a JSON-RPC call,
+// including the raw payload of lambdas.LambdaRequest
+func NewRequest(id int, method string, params mcp.MCPRequestParams) mcp.MCPRequest {
+	req := mcp.MCPRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params}
+	payload, _ := json.Marshal(req)
+	req.LambdaRequest = lambdas.LambdaRequest{Payload: payload}
+	return req
+}
+
+// Initialize runs initialize with the given client capabilities and keeps the session for later requests
+func (h *Harness) Initialize(capabilities map[string]any) *Response {
+	h.T.Helper()
+	response := h.Call("initialize", mcp.MCPRequestParams{
+		ProtocolVersion: client.DefaultProtocolVersion,
+		Capabilities:    capabilities,
+		ClientInfo:      map[string]any{"name": "mcptest", "version": "1.0.0"},
+	}).AssertOK()
+	h.SessionID = response.Header.Get(mcp.SessionHeader)
+	return response
+}
+
+// Call sends a request and collects its response
+func (h *Harness) Call(method string, params mcp.MCPRequestParams) *Response {
+	h.T.Helper()
+	h.nextID++
+	return h.Send(NewRequest(h.nextID, method, params))
+}
+
+// ListTools returns the tools of tools/list
+func (h *Harness) ListTools() []client.Tool {
+	h.T.Helper()
+	var result struct {
+		Tools []client.Tool `json:"tools"`
+	}
+	h.Call("tools/list", mcp.MCPRequestParams{}).AssertOK().DecodeResult(&result)
+	return result.Tools
+}
+
+// CallTool runs a tool with the given arguments
+func (h *Harness) CallTool(name string, arguments map[string]any) *Response {
+	h.T.Helper()
+	return h.Call("tools/call", mcp.MCPRequestParams{Name: name, Arguments: arguments})
+}
+
+// OverrideTool swaps the handler of a registered tool for the rest of the test, without notifying clients or
+// dropping the results the tool cached
+func (h *Harness) OverrideTool(name string, handler func(r *http.Request, params map[string]any) (any, error)) {
+	h.T.Helper()
+	restore, err := hooks.OverrideToolHandler(h.Server, name, handler)
+	if err != nil {
+		h.T.Fatalf("failed to override tool %s: %v", name, err)
+	}
+	h.T.Cleanup(func() {
+		if err := restore(); err != nil {
+			h.T.Errorf("failed to restore tool %s: %v", name, err)
+		}
+	})
+}
+
+// Send runs req through Server.Handle and collects every message of the response
+func (h *Harness) Send(req mcp.MCPRequest) *Response {
+	h.T.Helper()
+	w := httptest.NewRecorder()
+	body, err := h.Server.Handle(h.httpRequest(req), w, req)
+	if err != nil {
+		h.T.Fatalf("%s failed: %v", req.Method, err)
+	}
+
+	response := &Response{T: h.T, StatusCode: w.Code, Header: w.Header()}
+	if body == nil {
+		return response
+	}
+	defer body.Close()
+
+	var raw bytes.Buffer
+	err = client.ReadMessages(w.Header().Get("Content-Type"), io.TeeReader(body, &raw), func(message client.Message) error {
+		response.collect(message)
+		if message.IsRequest() {
+			h.answer(message)
+		}
+		return nil
+	})
+	response.Body = raw.Bytes()
+	if err != nil {
+		h.T.Fatalf("invalid %s response %q: %v", req.Method, raw.String(), err)
+	}
+	return response
+}
+
+// answer posts the reply to a server request, while the original response keeps streaming
+func (h *Harness) answer(request client.Message) {
+	var id int
+	json.Unmarshal(request.ID, &id)
+	reply := mcp.MCPRequest{JSONRPC: "2.0", ID: id}
+
+	if h.OnRequest == nil {
+		reply.Error = &mcp.JsonRPCError{Code: mcp.ErrMethodNotFound, Message: "mcptest: no OnRequest handler for " + request.Method}
+	} else if result, err := h.OnRequest(request.Method, request.Params); err != nil {
+		reply.Error = &mcp.JsonRPCError{Code: mcp.ErrInternal, Message: err.Error()}
+	} else {
+		if result == nil {
+			result = map[string]any{}
+		}
+		reply.Result, _ = json.Marshal(result)
+	}
+	payload, _ := json.Marshal(reply)
+	reply.LambdaRequest = lambdas.LambdaRequest{Payload: payload}
+
+	body, err := h.Server.Handle(h.httpRequest(reply), httptest.NewRecorder(), reply)
+	if err == nil && body != nil {
+		body.Close()
+	}
+}
+
+func (h *Harness) httpRequest(req mcp.MCPRequest) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(req.LambdaRequest.Payload))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "application/json, text/event-stream")
+	for name, values := range h.Header {
+		for _, value := range values {
+			r.Header.Add(name, value)
+		}
+	}
+	if h.SessionID != "" {
+		r.Header.Set(mcp.SessionHeader, h.SessionID)
+	}
+	return r
+}
@@ -0,0 +1,116 @@
+package mcptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+)
+
+func testServer() *mcp.Server {
+	server := mcp.NewServer("test", "1.0", "test server")
+	server.RegisterTool(mcp.ToolDescription{
+		Name: "clock",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return map[string]any{"zone": params["zone"], "now": time.Now().Format(time.RFC3339)}, nil
+		},
+	})
+	server.RegisterTool(mcp.ToolDescription{
+		Name: "letters",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return []string{"a", "b"}, nil
+		},
+	})
+	return server
+}
+
+func TestHarnessCallsTools(t *testing.T) {
+	h := New(t, testServer())
+	h.Initialize(nil)
+	if h.SessionID == "" {
+		t.Fatal("expected a session after initialize")
+	}
+
+	if tools := h.ListTools(); len(tools) != 2 {
+		t.Errorf("unexpected tools %v", tools)
+	}
+
+	var clock struct {
+		Zone string `json:"zone"`
+	}
+	h.CallTool("clock", map[string]any{"zone": "UTC"}).AssertOK().Decode(&clock)
+	if clock.Zone != "UTC" {
+		t.Errorf("unexpected result %+v", clock)
+	}
+
+	var letters struct {
+		Items []string `json:"items"`
+	}
+	h.CallTool("letters", nil).AssertOK().AssertProgress(2).Decode(&letters)
+	if len(letters.Items) != 2 {
+		t.Errorf("unexpected items %v", letters.Items)
+	}
+
+	h.CallTool("missing", nil).AssertError(mcp.ErrInvalidParams)
+}
+
+func TestHarnessGolden(t *testing.T) {
+	h := New(t, testServer())
+	h.CallTool("clock", map[string]any{"zone": "Europe/Madrid"}).AssertOK().AssertGolden(filepath.Join("testdata", "clock.golden.json"), "now")
+}
+
+func TestHarnessOverridesTools(t *testing.T) {
+	server := testServer()
+	t.Run("override", func(t *testing.T) {
+		h := New(t, server)
+		h.OverrideTool("clock", func(r *http.Request, params map[string]any) (any, error) {
+			return map[string]any{"zone": "fixed"}, nil
+		})
+		var clock map[string]any
+		h.CallTool("clock", nil).Decode(&clock)
+		if clock["zone"] != "fixed" {
+			t.Errorf("expected the override to run, got %v", clock)
+		}
+	})
+
+	var clock map[string]any
+	New(t, server).CallTool("clock", map[string]any{"zone": "UTC"}).Decode(&clock)
+	if clock["zone"] != "UTC" {
+		t.Errorf("expected the original handler after the test, got %v", clock)
+	}
+}
+
+func TestHarnessAnswersServerRequests(t *testing.T) {
+	server := mcp.NewServer("test", "1.0", "test server")
+	server.SetStreaming(true)
+	server.RegisterTool(mcp.ToolDescription{
+		Name: "confirm",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			confirmed, err := mcp.Confirm(r.Context(), "Send the push?")
+			return map[string]any{"confirmed": confirmed}, err
+		},
+	})
+
+	h := New(t, server)
+	h.OnRequest = func(method string, params json.RawMessage) (any, error) {
+		return mcp.ElicitationResult{Action: mcp.ElicitationAccept, Content: map[string]any{"confirm": true}}, nil
+	}
+	h.Initialize(map[string]any{"elicitation": map[string]any{}})
+
+	response := h.CallTool("confirm", nil).AssertOK()
+	if len(response.Requests) != 1 || response.Requests[0].Method != "elicitation/create" {
+		t.Errorf("expected one elicitation request, got %v", response.Requests)
+	}
+	var result map[string]bool
+	response.Decode(&result)
+	if !result["confirmed"] {
+		t.Errorf("expected a confirmation, got %v", result)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	Conformance(t, testServer(), WithToolCall("clock", map[string]any{"zone": "UTC"}), WithToolCall("letters", nil))
+}
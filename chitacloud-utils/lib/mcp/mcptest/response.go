@@ -0,0 +1,180 @@
+// Package mcptest provides an in-memory harness for testing MCP servers without a lambda runtime
+package mcptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp/client"
+)
+
+var update = flag.Bool("mcptest.update", false, "rewrite golden files of mcptest assertions")
+
+// Response is a decoded server response with the messages streamed before it
+type Response struct {
+	T          testing.TB
+	StatusCode int
+	Header     http.Header
+	// Body is the raw response body
+	Body []byte
+	// Messages are all the JSON-RPC messages of the body in order
+	Messages []client.Message
+
+	// Result and Error come from the final response message
+	Result json.RawMessage
+	Error  *mcp.JsonRPCError
+	// Progress holds the notifications/progress received before the response
+	Progress []client.Progress
+	// Notifications holds every other notification
+	Notifications []client.Message
+	// Requests holds the server-to-client requests received before the response
+	Requests []client.Message
+}
+
+func (r *Response) collect(message client.Message) {
+	r.Messages = append(r.Messages, message)
+	switch {
+	case message.IsResponse():
+		r.Result = message.Result
+		r.Error = message.Error
+	case message.IsRequest():
+		r.Requests = append(r.Requests, message)
+	case message.Method == "notifications/progress":
+		var progress client.Progress
+		if err := json.Unmarshal(message.Params, &progress); err == nil {
+			r.Progress = append(r.Progress, progress)
+		}
+	default:
+		r.Notifications = append(r.Notifications, message)
+	}
+}
+
+// AssertOK fails the test when the response is an error
+func (r *Response) AssertOK() *Response {
+	r.T.Helper()
+	if r.Error != nil {
+		r.T.Fatalf("unexpected error %d: %s", r.Error.Code, r.Error.Message)
+	}
+	if r.Result == nil {
+		r.T.Fatalf("no response message in body %q", r.Body)
+	}
+	return r
+}
+
+// AssertError fails the test unless the response is an error with the given code
+func (r *Response) AssertError(code int) *Response {
+	r.T.Helper()
+	if r.Error == nil {
+		r.T.Fatalf("expected error %d, got result %s", code, r.Result)
+	}
+	if r.Error.Code != code {
+		r.T.Fatalf("expected error %d, got %d: %s", code, r.Error.Code, r.Error.Message)
+	}
+	return r
+}
+
+// AssertStatus fails the test unless the HTTP status matches
+func (r *Response) AssertStatus(status int) *Response {
+	r.T.Helper()
+	if r.StatusCode != status {
+		r.T.Fatalf("expected HTTP status %d, got %d", status, r.StatusCode)
+	}
+	return r
+}
+
+// AssertProgress fails the test unless count progress notifications were received
+func (r *Response) AssertProgress(count int) *Response {
+	r.T.Helper()
+	if len(r.Progress) != count {
+		r.T.Fatalf("expected %d progress notifications, got %d", count, len(r.Progress))
+	}
+	return r
+}
+
+// DecodeResult decodes the result as returned by the server into v
+func (r *Response) DecodeResult(v any) *Response {
+	r.T.Helper()
+	if err := json.Unmarshal(r.Result, v); err != nil {
+		r.T.Fatalf("failed to decode result %s: %v", r.Result, err)
+	}
+	return r
+}
+
+// Decode decodes a tool result into v, using its structuredContent when the result is wrapped
+func (r *Response) Decode(v any) *Response {
+	r.T.Helper()
+	var wrapped struct {
+		StructuredContent json.RawMessage `json:"structuredContent"`
+	}
+	if err := json.Unmarshal(r.Result, &wrapped); err == nil && len(wrapped.StructuredContent) > 0 {
+		if err := json.Unmarshal(wrapped.StructuredContent, v); err != nil {
+			r.T.Fatalf("failed to decode structured content %s: %v", wrapped.StructuredContent, err)
+		}
+		return r
+	}
+	return r.DecodeResult(v)
+}
+
+// AssertGolden compares the result, or the structured content of tool results, with the JSON file at path,
+// ignoring the values of the masked keys at any depth, e.g. timestamps. Run the tests with -mcptest.update
+// to rewrite the file.
+func (r *Response) AssertGolden(path string, masked ...string) *Response {
+	r.T.Helper()
+	var result any
+	r.Decode(&result)
+	for _, key := range masked {
+		result = mask(result, key)
+	}
+	var formatted bytes.Buffer
+	encoder := json.NewEncoder(&formatted)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		r.T.Fatalf("failed to format result: %v", err)
+	}
+	actual := formatted.Bytes()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			r.T.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			r.T.Fatalf("failed to write golden file: %v", err)
+		}
+		return r
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		r.T.Fatalf("failed to read golden file (run with -mcptest.update to create it): %v", err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(actual)) {
+		r.T.Fatalf("result does not match %s\nexpected:\n%s\nactual:\n%s", path, expected, actual)
+	}
+	return r
+}
+
+// mask replaces the values stored under key with a placeholder
+func mask(value any, key string) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for k, v := range typed {
+			if k == key {
+				typed[k] = "<masked>"
+			} else {
+				typed[k] = mask(v, key)
+			}
+		}
+	case []any:
+		for i, v := range typed {
+			typed[i] = mask(v, key)
+		}
+	}
+	return value
+}
@@ -0,0 +1,242 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMetricsPath is the path metrics are served on when EnableMetrics gets an empty path
+const DefaultMetricsPath = "/metrics"
+
+// DefaultLatencyBuckets are the histogram upper bounds, in seconds, used for request latencies
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics records MCP request and tool call statistics and renders them in Prometheus text format
+type Metrics struct {
+	mu sync.Mutex
+
+	requests        *counterVec
+	requestErrors   *counterVec
+	requestDuration *histogramVec
+	toolCalls       *counterVec
+	toolErrors      *counterVec
+	toolDuration    *histogramVec
+	cacheHits       *counterVec
+	cacheMisses     *counterVec
+}
+
+// NewMetrics creates an empty metrics registry using buckets for latency histograms
+func NewMetrics(buckets []float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	return &Metrics{
+		requests:        newCounterVec("mcp_requests_total", "Total MCP requests by method.", "method"),
+		requestErrors:   newCounterVec("mcp_request_errors_total", "MCP requests answered with an error by method and JSON-RPC code.", "method", "code"),
+		requestDuration: newHistogramVec("mcp_request_duration_seconds", "MCP request latency by method.", buckets, "method"),
+		toolCalls:       newCounterVec("mcp_tool_calls_total", "Total tool calls by tool.", "tool"),
+		toolErrors:      newCounterVec("mcp_tool_errors_total", "Tool calls that failed by tool and JSON-RPC code.", "tool", "code"),
+		toolDuration:    newHistogramVec("mcp_tool_duration_seconds", "Tool handler latency by tool.", buckets, "tool"),
+		cacheHits:       newCounterVec("mcp_tool_cache_hits_total", "Tool calls served from the result cache by tool.", "tool"),
+		cacheMisses:     newCounterVec("mcp_tool_cache_misses_total", "Tool calls of cached tools that ran the handler by tool.", "tool"),
+	}
+}
+
+// EnableMetrics starts recording metrics and serves them on path, DefaultMetricsPath when empty
+func (s *Server) EnableMetrics(path string) *Metrics {
+	if path == "" {
+		path = DefaultMetricsPath
+	}
+	s.MetricsPath = path
+	if s.Metrics == nil {
+		s.Metrics = NewMetrics(nil)
+	}
+	return s.Metrics
+}
+
+// ObserveRequest records a handled request, err is the error returned to the client if any
+func (m *Metrics) ObserveRequest(method string, err error, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests.inc(method)
+	if err != nil {
+		m.requestErrors.inc(method, strconv.Itoa(rpcError(err, nil).Code))
+	}
+	m.requestDuration.observe(duration.Seconds(), method)
+}
+
+// ObserveToolCall records the execution of a tool handler
+func (m *Metrics) ObserveToolCall(tool string, err error, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toolCalls.inc(tool)
+	if err != nil {
+		m.toolErrors.inc(tool, strconv.Itoa(rpcError(err, nil).Code))
+	}
+	m.toolDuration.observe(duration.Seconds(), tool)
+}
+
+// ObserveCache records a lookup in the result cache of a tool
+func (m *Metrics) ObserveCache(tool string, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.cacheHits.inc(tool)
+	} else {
+		m.cacheMisses.inc(tool)
+	}
+}
+
+// WriteTo renders every metric in Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var buffer strings.Builder
+	m.requests.write(&buffer)
+	m.requestErrors.write(&buffer)
+	m.requestDuration.write(&buffer)
+	m.toolCalls.write(&buffer)
+	m.toolErrors.write(&buffer)
+	m.toolDuration.write(&buffer)
+	m.cacheHits.write(&buffer)
+	m.cacheMisses.write(&buffer)
+
+	n, err := io.WriteString(w, buffer.String())
+	return int64(n), err
+}
+
+// isMetricsRequest reports whether r asks for the metrics page
+func (s *Server) isMetricsRequest(r *http.Request) bool {
+	return s.Metrics != nil && s.MetricsPath != "" && r.Method == http.MethodGet && r.URL != nil && r.URL.Path == s.MetricsPath
+}
+
+// serveMetrics returns the metrics page as the response body
+func (s *Server) serveMetrics(w http.ResponseWriter) (io.ReadCloser, error) {
+	var buffer strings.Builder
+	if _, err := s.Metrics.WriteTo(&buffer); err != nil {
+		return nil, err
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	return io.NopCloser(strings.NewReader(buffer.String())), nil
+}
+
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+	values map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+}
+
+func (c *counterVec) inc(labelValues ...string) {
+	c.values[formatLabels(c.labels, labelValues)]++
+}
+
+func (c *counterVec) write(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, labels := range sortedKeys(c.values) {
+		fmt.Fprintf(b, "%s%s %s\n", c.name, labels, formatFloat(c.values[labels]))
+	}
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	values  map[string]*histogram
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return &histogramVec{name: name, help: help, labels: labels, buckets: sorted, values: map[string]*histogram{}}
+}
+
+func (h *histogramVec) observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	entry, ok := h.values[key]
+	if !ok {
+		entry = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = entry
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			entry.counts[i]++
+		}
+	}
+	entry.sum += value
+	entry.count++
+}
+
+func (h *histogramVec) write(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		entry := h.values[key]
+		labelValues := strings.Split(key, "\xff")
+		for i, bound := range h.buckets {
+			labels := formatLabels(append(append([]string{}, h.labels...), "le"), append(append([]string{}, labelValues...), formatFloat(bound)))
+			fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, labels, entry.counts[i])
+		}
+		labels := formatLabels(append(append([]string{}, h.labels...), "le"), append(append([]string{}, labelValues...), "+Inf"))
+		fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, labels, entry.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", h.name, formatLabels(h.labels, labelValues), formatFloat(entry.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", h.name, formatLabels(h.labels, labelValues), entry.count)
+	}
+}
+
+// formatLabels renders a Prometheus label set such as {method="tools/call"}
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
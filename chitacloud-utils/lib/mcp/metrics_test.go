@@ -0,0 +1,80 @@
+package mcp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.EnableMetrics("/internal/metrics")
+	server.RegisterTool(ToolDescription{
+		Name: "fails",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return nil, errors.New("boom")
+		},
+	})
+
+	for _, req := range []MCPRequest{
+		{JSONRPC: "2.0", ID: 1, Method: "tools/list"},
+		{JSONRPC: "2.0", ID: 2, Method: "tools/call", Params: MCPRequestParams{Name: "fails"}},
+		{JSONRPC: "2.0", ID: 3, Method: "something/custom"},
+	} {
+		if _, err := server.Handle(httptest.NewRequest("POST", "/", nil), httptest.NewRecorder(), req); err != nil {
+			t.Fatalf("Handle returned error: %v", err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	body, err := server.Handle(httptest.NewRequest("GET", "/internal/metrics", nil), w, MCPRequest{})
+	if err != nil {
+		t.Fatalf("metrics request returned error: %v", err)
+	}
+	b, _ := io.ReadAll(body)
+	page := string(b)
+
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	for _, line := range []string{
+		"# TYPE mcp_requests_total counter",
+		`mcp_requests_total{method="tools/list"} 1`,
+		`mcp_requests_total{method="default"} 1`,
+		`mcp_request_errors_total{method="tools/call",code="-32001"} 1`,
+		`mcp_tool_calls_total{tool="fails"} 1`,
+		`mcp_tool_errors_total{tool="fails",code="-32001"} 1`,
+		`mcp_tool_duration_seconds_bucket{tool="fails",le="+Inf"} 1`,
+		`mcp_tool_duration_seconds_count{tool="fails"} 1`,
+	} {
+		if !strings.Contains(page, line+"\n") {
+			t.Errorf("metrics page should contain %q:\n%s", line, page)
+		}
+	}
+}
+
+func TestRateLimitedMethodsUseBoundedLabels(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.EnableMetrics("/internal/metrics")
+	server.SetRateLimiter(NewRateLimiter(RateLimit{Rate: 0.001, Burst: 1}))
+
+	for i, method := range []string{"ping", "custom/one", "custom/two"} {
+		if _, err := server.Handle(httptest.NewRequest("POST", "/", nil), httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: i, Method: method}); err != nil {
+			t.Fatalf("Handle returned error: %v", err)
+		}
+	}
+
+	body, err := server.Handle(httptest.NewRequest("GET", "/internal/metrics", nil), httptest.NewRecorder(), MCPRequest{})
+	if err != nil {
+		t.Fatalf("metrics request returned error: %v", err)
+	}
+	b, _ := io.ReadAll(body)
+	page := string(b)
+
+	if !strings.Contains(page, `mcp_requests_total{method="default"} 2`+"\n") || strings.Contains(page, "custom/") {
+		t.Errorf("expected rate limited methods to be grouped under default:\n%s", page)
+	}
+}
@@ -0,0 +1,184 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultOpenAPIPath is the path the OpenAPI document is served on when EnableOpenAPI gets an empty path,
+// the document is also served on the path with a .json, .yaml or .yml extension
+const DefaultOpenAPIPath = "/.well-known/openapi"
+
+// ToolsPathPrefix is the path prefix of tool operations, relative to the REST base path
+const ToolsPathPrefix = "/tools/"
+
+const schemaComponentPrefix = "#/components/schemas/"
+
+// errorSchema describes the JSON-RPC error returned by failed tool calls
+var errorSchema = openapi3.NewObjectSchema().
+	WithProperty("code", openapi3.NewIntegerSchema()).
+	WithProperty("message", openapi3.NewStringSchema()).
+	WithProperty("data", &openapi3.Schema{}).
+	WithRequired([]string{"code", "message"})
+
+// EnableOpenAPI serves the OpenAPI document of the server tools on path, DefaultOpenAPIPath when empty
+func (s *Server) EnableOpenAPI(path string) {
+	if path == "" {
+		path = DefaultOpenAPIPath
+	}
+	for _, extension := range []string{".json", ".yaml", ".yml"} {
+		path = strings.TrimSuffix(path, extension)
+	}
+	s.OpenAPIPath = path
+}
+
+// OpenAPI describes every registered tool as a POST operation on its REST endpoint, with the input schema as
+// request body and the output schema as response body. ReadOnly tools taking only primitive arguments also get
+// a GET operation with query parameters. The document lists no operations while REST is disabled, as there is
+// no endpoint to call them on.
+func (s *Server) OpenAPI() *openapi3.T {
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       s.Name,
+			Version:     s.Version,
+			Description: s.Description,
+		},
+		// Operation paths are absolute on the host serving the document
+		Servers: openapi3.Servers{{URL: "/"}},
+		Paths:   openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{"Error": openapi3.NewSchemaRef("", errorSchema)},
+		},
+	}
+
+	if s.RESTPrefix == "" {
+		return doc
+	}
+	for _, tool := range s.Tools() {
+		input := tool.InputSchema
+		if input == nil {
+			input = openapi3.NewObjectSchema()
+		}
+		output := tool.OutputSchema
+		if output == nil {
+			output = &openapi3.Schema{}
+		}
+		collectSchemaComponents(doc.Components.Schemas, input)
+		collectSchemaComponents(doc.Components.Schemas, output)
+
+		operation := openapi3.NewOperation()
+		operation.OperationID = tool.Name
+		operation.Summary = tool.Name
+		operation.Description = tool.Description
+		operation.RequestBody = &openapi3.RequestBodyRef{
+			Value: openapi3.NewRequestBody().WithRequired(true).WithJSONSchema(input),
+		}
+		operation.AddResponse(http.StatusOK, openapi3.NewResponse().WithDescription("Tool result").WithJSONSchema(output))
+		operation.Responses.Set("default", &openapi3.ResponseRef{
+			Value: openapi3.NewResponse().WithDescription("Tool error").WithJSONSchemaRef(openapi3.NewSchemaRef(schemaComponentPrefix+"Error", errorSchema)),
+		})
+		item := &openapi3.PathItem{Post: operation}
+		if allowsGET(&tool) {
+			item.Get = queryOperation(operation, input)
+		}
+		doc.Paths.Set(s.RESTPrefix+tool.Name, item)
+	}
+	return doc
+}
+
+// queryOperation describes the GET variant of a tool operation, taking its arguments as query parameters
+func queryOperation(post *openapi3.Operation, input *openapi3.Schema) *openapi3.Operation {
+	operation := openapi3.NewOperation()
+	operation.OperationID = post.OperationID + "_get"
+	operation.Summary = post.Summary
+	operation.Description = post.Description
+	operation.Responses = post.Responses
+
+	names := make([]string, 0, len(input.Properties))
+	for name := range input.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parameter := openapi3.NewQueryParameter(name).WithSchema(input.Properties[name].Value)
+		parameter.Required = slices.Contains(input.Required, name)
+		operation.AddParameter(parameter)
+	}
+	return operation
+}
+
+// collectSchemaComponents registers the component schemas referenced by schema
+func collectSchemaComponents(components openapi3.Schemas, schema *openapi3.Schema) {
+	if schema == nil {
+		return
+	}
+	visit := func(ref *openapi3.SchemaRef) {
+		if ref == nil {
+			return
+		}
+		if name, ok := strings.CutPrefix(ref.Ref, schemaComponentPrefix); ok && ref.Value != nil {
+			if _, seen := components[name]; seen {
+				return
+			}
+			components[name] = openapi3.NewSchemaRef("", ref.Value)
+		}
+		collectSchemaComponents(components, ref.Value)
+	}
+	for _, property := range schema.Properties {
+		visit(property)
+	}
+	visit(schema.Items)
+	visit(schema.AdditionalProperties.Schema)
+	visit(schema.Not)
+	for _, refs := range []openapi3.SchemaRefs{schema.AllOf, schema.AnyOf, schema.OneOf} {
+		for _, ref := range refs {
+			visit(ref)
+		}
+	}
+}
+
+// isOpenAPIRequest reports whether r asks for the OpenAPI document
+func (s *Server) isOpenAPIRequest(r *http.Request) bool {
+	if s.OpenAPIPath == "" || r.Method != http.MethodGet || r.URL == nil {
+		return false
+	}
+	switch r.URL.Path {
+	case s.OpenAPIPath, s.OpenAPIPath + ".json", s.OpenAPIPath + ".yaml", s.OpenAPIPath + ".yml":
+		return true
+	}
+	return false
+}
+
+// serveOpenAPI returns the OpenAPI document as JSON, or as YAML when the extension or the Accept header asks for it
+func (s *Server) serveOpenAPI(r *http.Request, w http.ResponseWriter) (io.ReadCloser, error) {
+	body, err := json.Marshal(s.OpenAPI())
+	if err != nil {
+		return nil, err
+	}
+
+	wantsYAML := strings.HasSuffix(r.URL.Path, ".yaml") || strings.HasSuffix(r.URL.Path, ".yml") ||
+		(r.URL.Path == s.OpenAPIPath && strings.Contains(r.Header.Get("Accept"), "yaml"))
+	if !wantsYAML {
+		w.Header().Set("Content-Type", "application/json")
+		return io.NopCloser(strings.NewReader(string(body))), nil
+	}
+
+	var document any
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+	if body, err = yaml.Marshal(document); err != nil {
+		return nil, err
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	return io.NopCloser(strings.NewReader(string(body))), nil
+}
@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+func openAPITestServer() *Server {
+	server := NewServer("test", "1.2.3", "test server")
+	server.EnableREST("/")
+	address := &openapi3.SchemaRef{Ref: "#/components/schemas/Address", Value: openapi3.NewObjectSchema().WithProperty("city", openapi3.NewStringSchema())}
+	server.RegisterTool(ToolDescription{
+		Name:         "geocode",
+		Description:  "Find the address of a place",
+		ReadOnly:     true,
+		InputSchema:  openapi3.NewObjectSchema().WithProperty("place", openapi3.NewStringSchema()).WithRequired([]string{"place"}),
+		OutputSchema: openapi3.NewObjectSchema().WithPropertyRef("address", address),
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return map[string]any{"address": map[string]any{"city": params["place"]}}, nil
+		},
+	})
+	server.RegisterTool(ToolDescription{
+		Name:    "noop",
+		Handler: func(r *http.Request, params map[string]any) (any, error) { return nil, nil },
+	})
+	return server
+}
+
+func TestOpenAPIDescribesTools(t *testing.T) {
+	doc := openAPITestServer().OpenAPI()
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+	if doc.Info.Title != "test" || doc.Info.Version != "1.2.3" {
+		t.Errorf("unexpected info %+v", doc.Info)
+	}
+
+	geocode := doc.Paths.Find("/tools/geocode")
+	if geocode == nil || geocode.Post == nil || geocode.Post.OperationID != "geocode" {
+		t.Fatalf("expected a POST operation for geocode, got %+v", geocode)
+	}
+	request := geocode.Post.RequestBody.Value.Content.Get("application/json").Schema.Value
+	if len(request.Required) != 1 || request.Properties["place"] == nil {
+		t.Errorf("unexpected request schema %+v", request)
+	}
+	if response := geocode.Post.Responses.Status(http.StatusOK); response == nil || response.Value.Content.Get("application/json").Schema.Value.Properties["address"] == nil {
+		t.Errorf("unexpected response %+v", response)
+	}
+	if doc.Components.Schemas["Address"] == nil {
+		t.Error("expected referenced schemas in components")
+	}
+	if doc.Paths.Find("/tools/noop") == nil {
+		t.Error("expected an operation for tools without schemas")
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "/" {
+		t.Errorf("expected the serving host as server, got %v", doc.Servers)
+	}
+}
+
+func TestOpenAPIWithoutRESTListsNoOperations(t *testing.T) {
+	server := openAPITestServer()
+	server.RESTPrefix = ""
+	if paths := server.OpenAPI().Paths.Len(); paths != 0 {
+		t.Errorf("expected no operations without REST endpoints, got %d paths", paths)
+	}
+}
+
+func TestOpenAPIOperationsAreReachable(t *testing.T) {
+	server := openAPITestServer()
+	server.EnableREST("/api")
+	arguments := map[string]map[string]any{"geocode": {"place": "Madrid"}, "noop": {}}
+
+	doc := server.OpenAPI()
+	calls := 0
+	for path, item := range doc.Paths.Map() {
+		for method, operation := range item.Operations() {
+			name := strings.TrimSuffix(operation.OperationID, "_get")
+			var r *http.Request
+			if method == http.MethodGet {
+				query := url.Values{}
+				for key, value := range arguments[name] {
+					query.Set(key, value.(string))
+				}
+				r = httptest.NewRequest(method, path+"?"+query.Encode(), nil)
+			} else {
+				body, _ := json.Marshal(arguments[name])
+				r = httptest.NewRequest(method, path, strings.NewReader(string(body)))
+			}
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, r)
+
+			var result map[string]any
+			json.Unmarshal(w.Body.Bytes(), &result)
+			if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" || result["jsonrpc"] != nil {
+				t.Errorf("%s %s is not served by the tool: %d %q", method, path, w.Code, w.Body.String())
+			}
+			calls++
+		}
+	}
+	if calls != 3 {
+		t.Errorf("expected POST geocode, GET geocode and POST noop, got %d operations", calls)
+	}
+}
+
+func TestOpenAPIIsServedOnWellKnownPath(t *testing.T) {
+	server := openAPITestServer()
+	server.EnableOpenAPI("")
+
+	get := func(path, accept string) (*httptest.ResponseRecorder, string) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		body, err := server.Handle(r, w, MCPRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer body.Close()
+		payload, _ := io.ReadAll(body)
+		return w, string(payload)
+	}
+
+	w, body := get(DefaultOpenAPIPath, "application/json")
+	var document map[string]any
+	if err := json.Unmarshal([]byte(body), &document); err != nil || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a JSON document, got %q: %v", body, err)
+	}
+	if document["openapi"] != "3.0.3" {
+		t.Errorf("unexpected document %v", document)
+	}
+
+	for _, request := range []struct{ path, accept string }{{DefaultOpenAPIPath + ".yaml", ""}, {DefaultOpenAPIPath, "application/yaml"}} {
+		w, body := get(request.path, request.accept)
+		if err := yaml.Unmarshal([]byte(body), &document); err != nil || w.Header().Get("Content-Type") != "application/yaml" {
+			t.Fatalf("expected a YAML document on %s, got %q: %v", request.path, body, err)
+		}
+		if !strings.Contains(body, "/tools/geocode:") {
+			t.Errorf("expected the geocode operation in %q", body)
+		}
+	}
+}
@@ -0,0 +1,539 @@
+// Package openapiproxy registers one MCP tool per operation of an OpenAPI 3 document, proxying tool calls
+// to the HTTP API the document describes
+package openapiproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// BodyArgument is the tool argument holding the JSON request body of an operation
+const BodyArgument = "body"
+
+// DefaultMaxResponseBytes caps the upstream response bodies read when Proxy.MaxResponseBytes is zero
+const DefaultMaxResponseBytes = 10 << 20
+
+// maxErrorBodyBytes caps the upstream error body kept in UpstreamError
+const maxErrorBodyBytes = 4 << 10
+
+// ErrResponseTooLarge is returned when an upstream response body exceeds the proxy limit
+var ErrResponseTooLarge = errors.New("openapiproxy: upstream response too large")
+
+// ErrNoBaseURL is returned when neither the proxy nor the document declare where the API is served
+var ErrNoBaseURL = errors.New("openapiproxy: no base URL, set Proxy.BaseURL or declare a server in the document")
+
+// methods lists the HTTP methods of path items in the order their tools are registered
+var methods = []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodOptions, http.MethodHead, http.MethodPatch, http.MethodTrace}
+
+var invalidToolName = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// UpstreamError is returned by proxied tools when the API cannot be reached or answers with an HTTP error
+type UpstreamError struct {
+	Method string
+	// Path is the operation path of the document, the only location sent to MCP clients
+	Path string
+	// URL is the upstream request URL, for logging
+	URL string
+	// StatusCode is zero when no response was received
+	StatusCode int
+	// Body is the start of the upstream response body, for logging. It is never sent to MCP clients.
+	Body string
+	Err  error
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s %s failed: %v", e.Method, e.URL, e.Err)
+	}
+	return fmt.Sprintf("%s %s: unexpected HTTP status %d", e.Method, e.URL, e.StatusCode)
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// RPCError reports rejected arguments (400 and 422) as invalid params and the rest as internal errors,
+// naming the operation path rather than the upstream URL
+func (e *UpstreamError) RPCError() *mcp.JsonRPCError {
+	if e.StatusCode == 0 {
+		return &mcp.JsonRPCError{Code: mcp.ErrInternal, Message: fmt.Sprintf("%s %s: upstream request failed", e.Method, e.Path)}
+	}
+	code := mcp.ErrInternal
+	if e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity {
+		code = mcp.ErrInvalidParams
+	}
+	message := fmt.Sprintf("%s %s: unexpected HTTP status %d", e.Method, e.Path, e.StatusCode)
+	return &mcp.JsonRPCError{Code: code, Message: message, Data: map[string]any{"status": e.StatusCode}}
+}
+
+// Proxy turns the operations of an OpenAPI document into MCP tools that call BaseURL
+type Proxy struct {
+	Doc *openapi3.T
+	// BaseURL is the URL the operation paths are appended to, the first server of the document when empty
+	BaseURL string
+	// DocumentURL is where Doc was loaded from, relative server URLs of the document are resolved against it
+	DocumentURL string
+	// HTTPClient sends the upstream requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// Header holds extra headers sent with every upstream request, e.g. a static Authorization
+	Header http.Header
+	// ForwardHeaders lists headers of the incoming MCP request copied to upstream requests
+	ForwardHeaders []string
+	// Authorize sets credentials on each upstream request, incoming is the MCP request being served
+	Authorize func(upstream, incoming *http.Request) error
+	// ToolPrefix is prepended to the name of every tool, keeping several APIs apart on one server
+	ToolPrefix string
+	// MaxResponseBytes caps the upstream response bodies, DefaultMaxResponseBytes when zero
+	MaxResponseBytes int64
+}
+
+// New creates a proxy for the operations of doc
+func New(doc *openapi3.T) *Proxy {
+	return &Proxy{Doc: doc, Header: http.Header{}}
+}
+
+// LoadOption configures the loader used by Load
+type LoadOption func(*openapi3.Loader)
+
+// AllowExternalRefs lets the document reference other files and URLs. Only use it with trusted documents,
+// as the loader fetches whatever they point to.
+func AllowExternalRefs() LoadOption {
+	return func(loader *openapi3.Loader) {
+		loader.IsExternalRefsAllowed = true
+	}
+}
+
+// Load reads and validates the OpenAPI document at location, a file path or an http(s) URL. External
+// references are rejected unless AllowExternalRefs is given.
+func Load(ctx context.Context, location string, options ...LoadOption) (*Proxy, error) {
+	loader := openapi3.NewLoader()
+	loader.Context = ctx
+	for _, option := range options {
+		option(loader)
+	}
+
+	var doc *openapi3.T
+	var err error
+	remote := strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+	if remote {
+		var uri *url.URL
+		if uri, err = url.Parse(location); err == nil {
+			doc, err = loader.LoadFromURI(uri)
+		}
+	} else {
+		doc, err = loader.LoadFromFile(location)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI document %s: %w", location, err)
+	}
+	if err := doc.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document %s: %w", location, err)
+	}
+	proxy := New(doc)
+	if remote {
+		proxy.DocumentURL = location
+	}
+	return proxy, nil
+}
+
+// Register adds the tools of every operation to server
+func (p *Proxy) Register(server *mcp.Server) error {
+	tools, err := p.Tools()
+	if err != nil {
+		return err
+	}
+	for _, tool := range tools {
+		if err := server.RegisterTool(tool); err != nil {
+			return fmt.Errorf("failed to register tool %s: %w", tool.Name, err)
+		}
+	}
+	return nil
+}
+
+// Tools builds one tool per operation, named after its operationId, taking its parameters and request body
+// as arguments
+func (p *Proxy) Tools() ([]mcp.ToolDescription, error) {
+	if p.Doc == nil || p.Doc.Paths == nil {
+		return nil, nil
+	}
+	baseURL, err := p.baseURL()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := p.Doc.Paths.Map()
+	keys := make([]string, 0, len(paths))
+	for path := range paths {
+		keys = append(keys, path)
+	}
+	sort.Strings(keys)
+
+	var tools []mcp.ToolDescription
+	for _, path := range keys {
+		item := paths[path]
+		for _, method := range methods {
+			operation := item.GetOperation(method)
+			if operation == nil {
+				continue
+			}
+			op := &proxiedOperation{
+				proxy:      p,
+				method:     method,
+				path:       path,
+				baseURL:    baseURL,
+				parameters: mergeParameters(item.Parameters, operation.Parameters),
+				operation:  operation,
+			}
+			tools = append(tools, mcp.ToolDescription{
+				Name:         p.ToolPrefix + toolName(method, path, operation),
+				Description:  description(operation),
+				InputSchema:  op.inputSchema(),
+				OutputSchema: outputSchema(operation),
+				Handler:      op.call,
+			})
+		}
+	}
+	return tools, nil
+}
+
+func (p *Proxy) baseURL() (string, error) {
+	if p.BaseURL != "" {
+		return strings.TrimSuffix(p.BaseURL, "/"), nil
+	}
+	if len(p.Doc.Servers) == 0 || p.Doc.Servers[0].URL == "" {
+		return "", ErrNoBaseURL
+	}
+	server := p.Doc.Servers[0]
+	location := server.URL
+	// Server variables are replaced by their default values
+	for name, variable := range server.Variables {
+		location = strings.ReplaceAll(location, "{"+name+"}", variable.Default)
+	}
+	resolved, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL %s: %w", location, err)
+	}
+	if !resolved.IsAbs() {
+		// Relative server URLs are relative to the document, as in "/v1" or "../api"
+		if p.DocumentURL == "" {
+			return "", fmt.Errorf("%w: server URL %s is relative to an unknown document URL", ErrNoBaseURL, location)
+		}
+		document, err := url.Parse(p.DocumentURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid document URL %s: %w", p.DocumentURL, err)
+		}
+		location = document.ResolveReference(resolved).String()
+	}
+	return strings.TrimSuffix(location, "/"), nil
+}
+
+// reservedHeaders are the headers set by Header and ForwardHeaders, which tool arguments cannot override
+func (p *Proxy) reservedHeaders() map[string]bool {
+	reserved := map[string]bool{}
+	for name := range p.Header {
+		reserved[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, name := range p.ForwardHeaders {
+		reserved[http.CanonicalHeaderKey(name)] = true
+	}
+	return reserved
+}
+
+// isArgument reports whether parameter is taken from the tool arguments. Cookie parameters are left to
+// Header, ForwardHeaders or Authorize, as they usually carry credentials, and so are headers these set.
+func isArgument(parameter *openapi3.Parameter, reserved map[string]bool) bool {
+	switch parameter.In {
+	case openapi3.ParameterInCookie:
+		return false
+	case openapi3.ParameterInHeader:
+		return !reserved[http.CanonicalHeaderKey(parameter.Name)]
+	}
+	return true
+}
+
+func (p *Proxy) maxResponseBytes() int64 {
+	if p.MaxResponseBytes > 0 {
+		return p.MaxResponseBytes
+	}
+	return DefaultMaxResponseBytes
+}
+
+func (p *Proxy) httpClient() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// toolName uses the operationId, or the method and path when the operation has none
+func toolName(method, path string, operation *openapi3.Operation) string {
+	name := operation.OperationID
+	if name == "" {
+		name = strings.ToLower(method) + "_" + strings.Trim(path, "/")
+	}
+	return strings.Trim(invalidToolName.ReplaceAllString(name, "_"), "_")
+}
+
+func description(operation *openapi3.Operation) string {
+	switch {
+	case operation.Summary == "":
+		return operation.Description
+	case operation.Description == "":
+		return operation.Summary
+	}
+	return operation.Summary + "\n\n" + operation.Description
+}
+
+// mergeParameters applies the operation parameters over the parameters shared by the path item
+func mergeParameters(shared, own openapi3.Parameters) []*openapi3.Parameter {
+	var merged []*openapi3.Parameter
+	index := map[string]int{}
+	for _, refs := range []openapi3.Parameters{shared, own} {
+		for _, ref := range refs {
+			if ref == nil || ref.Value == nil {
+				continue
+			}
+			key := ref.Value.In + ":" + ref.Value.Name
+			if i, ok := index[key]; ok {
+				merged[i] = ref.Value
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, ref.Value)
+		}
+	}
+	return merged
+}
+
+// outputSchema is the JSON schema of the first successful response, when it describes an object
+func outputSchema(operation *openapi3.Operation) *openapi3.Schema {
+	if operation.Responses == nil {
+		return nil
+	}
+	for _, status := range []int{http.StatusOK, http.StatusCreated, http.StatusAccepted} {
+		response := operation.Responses.Status(status)
+		if response == nil || response.Value == nil {
+			continue
+		}
+		media := response.Value.Content.Get("application/json")
+		if media == nil || media.Schema == nil || media.Schema.Value == nil {
+			return nil
+		}
+		if schema := media.Schema.Value; schema.Type != nil && schema.Type.Is(openapi3.TypeObject) {
+			return schema
+		}
+		return nil
+	}
+	return nil
+}
+
+// proxiedOperation executes the calls of one tool
+type proxiedOperation struct {
+	proxy      *Proxy
+	method     string
+	path       string
+	baseURL    string
+	parameters []*openapi3.Parameter
+	operation  *openapi3.Operation
+}
+
+// inputSchema describes the argument parameters as properties, plus the JSON request body as BodyArgument
+func (o *proxiedOperation) inputSchema() *openapi3.Schema {
+	schema := openapi3.NewObjectSchema()
+	reserved := o.proxy.reservedHeaders()
+	for _, parameter := range o.parameters {
+		if !isArgument(parameter, reserved) {
+			continue
+		}
+		property := &openapi3.Schema{}
+		if parameter.Schema != nil && parameter.Schema.Value != nil {
+			copied := *parameter.Schema.Value
+			property = &copied
+		}
+		if property.Description == "" {
+			property.Description = parameter.Description
+		}
+		schema.Properties[parameter.Name] = openapi3.NewSchemaRef("", property)
+		if parameter.Required {
+			schema.Required = append(schema.Required, parameter.Name)
+		}
+	}
+
+	if body := o.requestBody(); body != nil {
+		if media := body.Content.Get("application/json"); media != nil && media.Schema != nil {
+			schema.Properties[BodyArgument] = media.Schema
+			if body.Required {
+				schema.Required = append(schema.Required, BodyArgument)
+			}
+		}
+	}
+	return schema
+}
+
+func (o *proxiedOperation) requestBody() *openapi3.RequestBody {
+	if o.operation.RequestBody == nil {
+		return nil
+	}
+	return o.operation.RequestBody.Value
+}
+
+// call issues the HTTP request of the operation and returns the decoded response body
+func (o *proxiedOperation) call(r *http.Request, params map[string]any) (any, error) {
+	upstream, err := o.newRequest(r, params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.proxy.httpClient().Do(upstream)
+	if err != nil {
+		return nil, &UpstreamError{Method: o.method, Path: o.path, URL: upstream.URL.String(), Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		payload, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return nil, &UpstreamError{Method: o.method, Path: o.path, URL: upstream.URL.String(), StatusCode: resp.StatusCode, Body: string(payload)}
+	}
+	limit := o.proxy.maxResponseBytes()
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, &UpstreamError{Method: o.method, Path: o.path, URL: upstream.URL.String(), Err: err}
+	}
+	if int64(len(payload)) > limit {
+		return nil, fmt.Errorf("%w: %s %s exceeds %d bytes", ErrResponseTooLarge, o.method, o.path, limit)
+	}
+
+	if len(bytes.TrimSpace(payload)) == 0 {
+		return map[string]any{"status": resp.StatusCode}, nil
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return map[string]any{"status": resp.StatusCode, "contentType": resp.Header.Get("Content-Type"), "text": string(payload)}, nil
+	}
+	var result any
+	if err := json.Unmarshal(payload, &result); err != nil {
+		return nil, fmt.Errorf("invalid JSON in %s %s response: %w", o.method, o.path, err)
+	}
+	return result, nil
+}
+
+// newRequest places the arguments in the path, query, headers and body of the upstream request. Header and
+// ForwardHeaders are applied last, so arguments cannot replace the credentials they carry.
+func (o *proxiedOperation) newRequest(r *http.Request, params map[string]any) (*http.Request, error) {
+	path := o.path
+	query := url.Values{}
+	header := http.Header{}
+	reserved := o.proxy.reservedHeaders()
+	for _, parameter := range o.parameters {
+		if !isArgument(parameter, reserved) {
+			continue
+		}
+		value, ok := params[parameter.Name]
+		if !ok || value == nil {
+			if parameter.Required {
+				return nil, &mcp.JsonRPCError{Code: mcp.ErrInvalidParams, Message: "missing required argument " + parameter.Name}
+			}
+			continue
+		}
+		switch parameter.In {
+		case openapi3.ParameterInPath:
+			path = strings.ReplaceAll(path, "{"+parameter.Name+"}", url.PathEscape(formatValue(value)))
+		case openapi3.ParameterInQuery:
+			if values, isList := value.([]any); isList {
+				for _, item := range values {
+					query.Add(parameter.Name, formatValue(item))
+				}
+			} else {
+				query.Set(parameter.Name, formatValue(value))
+			}
+		case openapi3.ParameterInHeader:
+			header.Set(parameter.Name, formatValue(value))
+		}
+	}
+
+	if err := checkPathSegments(o.path, path); err != nil {
+		return nil, err
+	}
+	target := o.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if value, ok := params[BodyArgument]; ok && o.requestBody() != nil {
+		payload, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		body = bytes.NewReader(payload)
+		header.Set("Content-Type", "application/json")
+	} else if requestBody := o.requestBody(); requestBody != nil && requestBody.Required {
+		return nil, &mcp.JsonRPCError{Code: mcp.ErrInvalidParams, Message: "missing required argument " + BodyArgument}
+	}
+
+	upstream, err := http.NewRequestWithContext(r.Context(), o.method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", o.method, err)
+	}
+	upstream.Header.Set("Accept", "application/json")
+	for name, values := range header {
+		upstream.Header[name] = values
+	}
+	for name, values := range o.proxy.Header {
+		upstream.Header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+	}
+	for _, name := range o.proxy.ForwardHeaders {
+		if value := r.Header.Get(name); value != "" {
+			upstream.Header.Set(name, value)
+		}
+	}
+	mcp.InjectTraceContext(r.Context(), upstream.Header)
+	if o.proxy.Authorize != nil {
+		if err := o.proxy.Authorize(upstream, r); err != nil {
+			return nil, fmt.Errorf("failed to authorize %s request: %w", o.method, err)
+		}
+	}
+	return upstream, nil
+}
+
+// checkPathSegments rejects arguments that make a templated path segment empty, "." or "..", which would
+// point the request at another upstream path
+func checkPathSegments(template, path string) error {
+	// Path arguments are escaped, so both paths have the same segments
+	templates := strings.Split(template, "/")
+	for i, segment := range strings.Split(path, "/") {
+		if !strings.Contains(templates[i], "{") {
+			continue
+		}
+		if segment == "" || segment == "." || segment == ".." {
+			return &mcp.JsonRPCError{Code: mcp.ErrInvalidParams, Message: fmt.Sprintf("invalid path segment %q", segment)}
+		}
+	}
+	return nil
+}
+
+// formatValue renders an argument as a path, query or header value
+func formatValue(value any) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case map[string]any, []any:
+		encoded, _ := json.Marshal(typed)
+		return string(encoded)
+	}
+	return fmt.Sprint(value)
+}
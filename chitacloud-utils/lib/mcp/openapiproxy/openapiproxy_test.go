@@ -0,0 +1,295 @@
+package openapiproxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp/mcptest"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// upstreamCall records what the stand-in API received
+type upstreamCall struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   string
+}
+
+func newPetstore(t *testing.T) (*httptest.Server, *[]upstreamCall) {
+	t.Helper()
+	var calls []upstreamCall
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls = append(calls, upstreamCall{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, header: r.Header.Clone(), body: string(body)})
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/pets/42":
+			w.Write([]byte(`{"id": 42, "name": "Rex"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/pets" && strings.Contains(string(body), "invalid"):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message": "invalid pet"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/pets":
+			w.Write([]byte(`[{"id": 42, "name": "Rex"}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/pets":
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "no such pet"}`))
+		}
+	}))
+	t.Cleanup(api.Close)
+	return api, &calls
+}
+
+func loadPetstore(t *testing.T, baseURL string) *Proxy {
+	t.Helper()
+	proxy, err := Load(context.Background(), filepath.Join("testdata", "petstore.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy.BaseURL = baseURL
+	return proxy
+}
+
+func TestToolsFromOperations(t *testing.T) {
+	proxy := loadPetstore(t, "")
+	tools, err := proxy.Tools()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	byName := map[string]mcp.ToolDescription{}
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+		byName[tool.Name] = tool
+	}
+	if len(names) != 4 || names[0] != "listPets" || names[1] != "createPet" || names[2] != "get_pets_petId" || names[3] != "deletePet" {
+		t.Fatalf("unexpected tools %v", names)
+	}
+
+	list := byName["listPets"].InputSchema
+	if list.Properties["limit"].Value.Description != "How many pets to return" || len(list.Required) != 0 {
+		t.Errorf("unexpected listPets input %+v", list)
+	}
+	if byName["listPets"].OutputSchema != nil {
+		t.Error("array responses cannot be output schemas")
+	}
+
+	create := byName["createPet"]
+	if create.InputSchema.Properties[BodyArgument] == nil || create.InputSchema.Required[0] != BodyArgument {
+		t.Errorf("expected a required body argument, got %+v", create.InputSchema)
+	}
+	if create.OutputSchema == nil || create.OutputSchema.Properties["name"] == nil {
+		t.Errorf("expected the Pet output schema, got %+v", create.OutputSchema)
+	}
+
+	get := byName["get_pets_petId"].InputSchema
+	if get.Properties["petId"] == nil || get.Properties["X-Request-Id"] == nil || get.Required[0] != "petId" {
+		t.Errorf("expected path item and operation parameters, got %+v", get)
+	}
+	if get.Properties["session"] != nil || len(get.Required) != 1 {
+		t.Errorf("expected cookie parameters to be left out, got %+v", get)
+	}
+
+	if base, err := proxy.baseURL(); err != nil || base != "https://petstore.example.com/v1" {
+		t.Errorf("expected the server URL with its variables, got %q: %v", base, err)
+	}
+}
+
+func TestProxiedCalls(t *testing.T) {
+	api, calls := newPetstore(t)
+	proxy := loadPetstore(t, api.URL+"/v1")
+	proxy.Header.Set("X-Api-Key", "static")
+	proxy.ForwardHeaders = []string{"Authorization"}
+	proxy.Authorize = func(upstream, incoming *http.Request) error {
+		upstream.Header.Set("X-Tenant", "acme")
+		return nil
+	}
+
+	server := mcp.NewServer("petstore", "1.0", "petstore proxy")
+	if err := proxy.Register(server); err != nil {
+		t.Fatal(err)
+	}
+	h := mcptest.New(t, server)
+	h.Header.Set("Authorization", "Bearer user-token")
+
+	var pet map[string]any
+	h.CallTool("get_pets_petId", map[string]any{"petId": "42", "X-Request-Id": "req-1"}).AssertOK().Decode(&pet)
+	if pet["name"] != "Rex" {
+		t.Errorf("unexpected pet %v", pet)
+	}
+	call := (*calls)[0]
+	if call.header.Get("Authorization") != "Bearer user-token" || call.header.Get("X-Api-Key") != "static" ||
+		call.header.Get("X-Tenant") != "acme" || call.header.Get("X-Request-Id") != "req-1" {
+		t.Errorf("unexpected upstream headers %v", call.header)
+	}
+
+	h.CallTool("createPet", map[string]any{"body": map[string]any{"id": 7, "name": "Tom"}}).AssertOK().Decode(&pet)
+	if pet["name"] != "Tom" {
+		t.Errorf("unexpected created pet %v", pet)
+	}
+	var sent map[string]any
+	if err := json.Unmarshal([]byte((*calls)[1].body), &sent); err != nil || sent["id"] != float64(7) {
+		t.Errorf("unexpected upstream body %q", (*calls)[1].body)
+	}
+
+	h.CallTool("listPets", map[string]any{"limit": 10, "tag": []any{"cat", "dog"}})
+	if query := (*calls)[2].query; query != "limit=10&tag=cat&tag=dog" {
+		t.Errorf("unexpected upstream query %q", query)
+	}
+
+	var deleted map[string]any
+	h.CallTool("deletePet", map[string]any{"petId": "42"}).AssertOK().Decode(&deleted)
+	if deleted["status"] != float64(http.StatusNoContent) {
+		t.Errorf("unexpected delete result %v", deleted)
+	}
+}
+
+func TestProxiedErrors(t *testing.T) {
+	api, calls := newPetstore(t)
+	proxy := loadPetstore(t, api.URL+"/v1")
+	server := mcp.NewServer("petstore", "1.0", "petstore proxy")
+	if err := proxy.Register(server); err != nil {
+		t.Fatal(err)
+	}
+	h := mcptest.New(t, server)
+
+	response := h.CallTool("get_pets_petId", map[string]any{"petId": "0"}).AssertError(mcp.ErrInternal)
+	if data, _ := response.Error.Data.(map[string]any); data["status"] != float64(http.StatusNotFound) || data["body"] != nil {
+		t.Errorf("expected only the upstream status in the error, got %v", response.Error.Data)
+	}
+	if strings.Contains(response.Error.Message, "no such pet") {
+		t.Errorf("expected the upstream body to stay out of the error, got %q", response.Error.Message)
+	}
+
+	if strings.Contains(response.Error.Message, api.URL) || !strings.Contains(response.Error.Message, "/pets/{petId}") {
+		t.Errorf("expected the operation path instead of the upstream URL, got %q", response.Error.Message)
+	}
+	h.CallTool("createPet", map[string]any{"body": map[string]any{"id": 7, "name": "invalid"}}).AssertError(mcp.ErrInvalidParams)
+
+	h.CallTool("createPet", nil).AssertError(mcp.ErrInvalidParams)
+	h.CallTool("get_pets_petId", nil).AssertError(mcp.ErrInvalidParams)
+	for _, petID := range []string{"", ".", ".."} {
+		h.CallTool("get_pets_petId", map[string]any{"petId": petID}).AssertError(mcp.ErrInvalidParams)
+	}
+	if len(*calls) != 2 {
+		t.Errorf("expected invalid calls to stay local, got %d upstream calls", len(*calls))
+	}
+
+	var upstreamErr *UpstreamError
+	_, err := (&proxiedOperation{proxy: proxy, method: http.MethodGet, path: "/missing", baseURL: api.URL, operation: &openapi3.Operation{}}).call(httptest.NewRequest(http.MethodPost, "/", nil), nil)
+	if !errors.As(err, &upstreamErr) || upstreamErr.StatusCode != http.StatusNotFound || !strings.Contains(upstreamErr.Body, "no such pet") {
+		t.Errorf("expected an UpstreamError, got %v", err)
+	}
+}
+
+func TestArgumentsCannotOverrideHeaders(t *testing.T) {
+	api, calls := newPetstore(t)
+	proxy := loadPetstore(t, api.URL+"/v1")
+	proxy.Header.Set("X-Request-Id", "configured")
+	server := mcp.NewServer("petstore", "1.0", "petstore proxy")
+	if err := proxy.Register(server); err != nil {
+		t.Fatal(err)
+	}
+	if tool := server.FindTool("get_pets_petId"); tool.InputSchema.Properties["X-Request-Id"] != nil {
+		t.Errorf("expected the configured header to be left out of the arguments, got %+v", tool.InputSchema)
+	}
+
+	h := mcptest.New(t, server)
+	h.CallTool("get_pets_petId", map[string]any{"petId": "42", "X-Request-Id": "argument"}).AssertOK()
+	if got := (*calls)[0].header.Values("X-Request-Id"); len(got) != 1 || got[0] != "configured" {
+		t.Errorf("expected the configured header upstream, got %v", got)
+	}
+}
+
+func TestProxiedResponseLimit(t *testing.T) {
+	api, _ := newPetstore(t)
+	proxy := loadPetstore(t, api.URL+"/v1")
+	proxy.MaxResponseBytes = 8
+	tools, err := proxy.Tools()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tools[0].Handler(httptest.NewRequest(http.MethodPost, "/", nil), nil); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+func TestNoBaseURL(t *testing.T) {
+	proxy := loadPetstore(t, "")
+	proxy.Doc.Servers = nil
+	if _, err := proxy.Tools(); !errors.Is(err, ErrNoBaseURL) {
+		t.Errorf("expected ErrNoBaseURL, got %v", err)
+	}
+
+	proxy.Doc.Servers = openapi3.Servers{{URL: "/v1"}}
+	if _, err := proxy.Tools(); !errors.Is(err, ErrNoBaseURL) {
+		t.Errorf("expected ErrNoBaseURL for a relative server of a local document, got %v", err)
+	}
+}
+
+func TestRelativeServerURL(t *testing.T) {
+	spec, err := os.ReadFile(filepath.Join("testdata", "petstore.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec = []byte(strings.Replace(string(spec), "https://petstore.example.com/{version}", "../{version}", 1))
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(spec)
+	}))
+	defer api.Close()
+
+	proxy, err := Load(context.Background(), api.URL+"/specs/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base, err := proxy.baseURL(); err != nil || base != api.URL+"/v1" {
+		t.Errorf("expected the server URL resolved against the document, got %q: %v", base, err)
+	}
+}
+
+func TestExternalRefs(t *testing.T) {
+	dir := t.TempDir()
+	doc := `openapi: 3.0.3
+info: {title: pets, version: "1.0"}
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: a pet
+          content:
+            application/json:
+              schema: {$ref: "pet.yaml#/Pet"}
+`
+	if err := os.WriteFile(filepath.Join(dir, "api.yaml"), []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pet.yaml"), []byte("Pet: {type: object}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(context.Background(), filepath.Join(dir, "api.yaml")); err == nil {
+		t.Error("expected external references to be rejected by default")
+	}
+	if _, err := Load(context.Background(), filepath.Join(dir, "api.yaml"), AllowExternalRefs()); err != nil {
+		t.Errorf("expected AllowExternalRefs to load the document, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// OTLPFileExporter appends spans to a file as OTLP/JSON ExportTraceServiceRequest documents, one per line.
+// The file is opened on the first export and kept open until Close.
+type OTLPFileExporter struct {
+	Path        string
+	ServiceName string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewOTLPFileExporter creates an exporter writing to path on behalf of serviceName
+func NewOTLPFileExporter(path, serviceName string) *OTLPFileExporter {
+	return &OTLPFileExporter{Path: path, ServiceName: serviceName}
+}
+
+func (e *OTLPFileExporter) ExportSpans(_ context.Context, spans []*Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	line, err := json.Marshal(OTLPTraceRequest(e.ServiceName, spans))
+	if err != nil {
+		return fmt.Errorf("failed to marshal spans: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.file == nil {
+		file, err := os.OpenFile(e.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open span file: %w", err)
+		}
+		e.file = file
+	}
+	if _, err := e.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write spans: %w", err)
+	}
+	return nil
+}
+
+// Close closes the span file, a later export opens it again
+func (e *OTLPFileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return nil
+	}
+	err := e.file.Close()
+	e.file = nil
+	return err
+}
+
+// OTLPTraceRequest builds the OTLP/JSON representation of spans
+func OTLPTraceRequest(serviceName string, spans []*Span) map[string]any {
+	otlpSpans := make([]map[string]any, 0, len(spans))
+	for _, span := range spans {
+		otlpSpans = append(otlpSpans, otlpSpan(span))
+	}
+
+	return map[string]any{
+		"resourceSpans": []map[string]any{
+			{
+				"resource": map[string]any{
+					"attributes": otlpAttributes(map[string]any{"service.name": serviceName}),
+				},
+				"scopeSpans": []map[string]any{
+					{
+						"scope": map[string]any{"name": "github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"},
+						"spans": otlpSpans,
+					},
+				},
+			},
+		},
+	}
+}
+
+func otlpSpan(span *Span) map[string]any {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	out := map[string]any{
+		"traceId":           span.SpanContext.TraceID,
+		"spanId":            span.SpanContext.SpanID,
+		"name":              span.Name,
+		"kind":              span.Kind,
+		"startTimeUnixNano": strconv.FormatInt(span.StartTime.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+		"attributes":        otlpAttributes(span.Attributes),
+		"status":            map[string]any{"code": span.StatusCode, "message": span.StatusMessage},
+	}
+	if span.ParentSpanID != "" {
+		out["parentSpanId"] = span.ParentSpanID
+	}
+	if span.SpanContext.TraceState != "" {
+		out["traceState"] = span.SpanContext.TraceState
+	}
+	return out
+}
+
+// otlpAttributes converts attributes to OTLP key/value pairs sorted by key
+func otlpAttributes(attributes map[string]any) []map[string]any {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]map[string]any, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, map[string]any{"key": key, "value": otlpValue(attributes[key])})
+	}
+	return out
+}
+
+func otlpValue(value any) map[string]any {
+	switch v := value.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
@@ -0,0 +1,133 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrPromptExists is returned when registering a prompt whose name is already taken
+var ErrPromptExists = errors.New("prompt already registered")
+
+// PromptArgument describes an argument accepted by a prompt
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// Prompt is a message template offered to the user, rendered by Handler with the arguments the user filled
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+
+	Handler func(r *http.Request, arguments map[string]string) (*PromptResult, error) `json:"-"`
+}
+
+// PromptResult is the result of prompts/get, its messages are usually built with TextMessage
+type PromptResult struct {
+	Description string            `json:"description,omitempty"`
+	Messages    []SamplingMessage `json:"messages"`
+}
+
+// RegisterPrompt adds a prompt to the server, failing with ErrPromptExists on duplicate names
+func (s *Server) RegisterPrompt(prompt Prompt) error {
+	if prompt.Name == "" {
+		return errors.New("prompt name is required")
+	}
+	if prompt.Handler == nil {
+		return fmt.Errorf("prompt %s has no handler", prompt.Name)
+	}
+
+	s.promptsMu.Lock()
+	if _, ok := s.prompts[prompt.Name]; ok {
+		s.promptsMu.Unlock()
+		return fmt.Errorf("%w: %s", ErrPromptExists, prompt.Name)
+	}
+	if s.prompts == nil {
+		s.prompts = map[string]Prompt{}
+	}
+	s.prompts[prompt.Name] = prompt
+	s.promptOrder = append(s.promptOrder, prompt.Name)
+	s.promptsMu.Unlock()
+
+	s.NotifyAll("notifications/prompts/list_changed", nil)
+	return nil
+}
+
+// UnregisterPrompt removes a prompt from the server
+func (s *Server) UnregisterPrompt(name string) error {
+	s.promptsMu.Lock()
+	if _, ok := s.prompts[name]; !ok {
+		s.promptsMu.Unlock()
+		return fmt.Errorf("prompt not found: %s", name)
+	}
+	delete(s.prompts, name)
+	for i, existing := range s.promptOrder {
+		if existing == name {
+			s.promptOrder = append(s.promptOrder[:i:i], s.promptOrder[i+1:]...)
+			break
+		}
+	}
+	s.promptsMu.Unlock()
+
+	s.NotifyAll("notifications/prompts/list_changed", nil)
+	return nil
+}
+
+// FindPrompt returns the prompt registered under name, nil when missing
+func (s *Server) FindPrompt(name string) *Prompt {
+	s.promptsMu.RLock()
+	defer s.promptsMu.RUnlock()
+	if prompt, ok := s.prompts[name]; ok {
+		return &prompt
+	}
+	return nil
+}
+
+// Prompts returns a snapshot of the registered prompts in registration order
+func (s *Server) Prompts() []Prompt {
+	s.promptsMu.RLock()
+	defer s.promptsMu.RUnlock()
+	prompts := make([]Prompt, 0, len(s.promptOrder))
+	for _, name := range s.promptOrder {
+		prompts = append(prompts, s.prompts[name])
+	}
+	return prompts
+}
+
+// handleGetPrompt implements prompts/get, checking that the required arguments are filled
+func (s *Server) handleGetPrompt(r *http.Request, name string, params map[string]any) (any, error) {
+	prompt := s.FindPrompt(name)
+	if prompt == nil {
+		return nil, &JsonRPCError{Code: ErrInvalidParams, Message: "prompt not found: " + name}
+	}
+
+	arguments := make(map[string]string, len(params))
+	for key, value := range params {
+		text, ok := value.(string)
+		if !ok {
+			return nil, &JsonRPCError{Code: ErrInvalidParams, Message: fmt.Sprintf("prompt argument %s must be a string", key)}
+		}
+		arguments[key] = text
+	}
+	for _, argument := range prompt.Arguments {
+		if argument.Required && arguments[argument.Name] == "" {
+			return nil, &JsonRPCError{Code: ErrInvalidParams, Message: "missing required prompt argument " + argument.Name}
+		}
+	}
+
+	result, err := prompt.Handler(r, arguments)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = &PromptResult{}
+	}
+	if result.Messages == nil {
+		result.Messages = []SamplingMessage{}
+	}
+	return result, nil
+}
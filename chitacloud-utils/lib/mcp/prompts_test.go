@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func hourPrompt() Prompt {
+	return Prompt{
+		Name:        "plan_meeting",
+		Description: "Plan a meeting in a timezone",
+		Arguments:   []PromptArgument{{Name: "timezone", Required: true}, {Name: "topic"}},
+		Handler: func(r *http.Request, arguments map[string]string) (*PromptResult, error) {
+			return &PromptResult{Messages: []SamplingMessage{TextMessage("user", "Plan a meeting in "+arguments["timezone"])}}, nil
+		},
+	}
+}
+
+func callPromptMethod(t *testing.T, server *Server, method string, params MCPRequestParams) map[string]any {
+	t.Helper()
+	body, err := server.Handle(httptest.NewRequest("POST", "/", nil), httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: params})
+	if err != nil {
+		t.Fatalf("%s failed: %v", method, err)
+	}
+	defer body.Close()
+	return responseMessages(t, body)[0]
+}
+
+func TestPrompts(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	if err := server.RegisterPrompt(hourPrompt()); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterPrompt(hourPrompt()); err == nil {
+		t.Error("expected duplicate prompts to be rejected")
+	}
+	if _, ok := server.HandleInitialize()["capabilities"].(map[string]any)["prompts"]; !ok {
+		t.Error("expected the prompts capability")
+	}
+
+	prompts := callPromptMethod(t, server, "prompts/list", MCPRequestParams{})["result"].(map[string]any)["prompts"].([]any)
+	if len(prompts) != 1 || prompts[0].(map[string]any)["name"] != "plan_meeting" || len(prompts[0].(map[string]any)["arguments"].([]any)) != 2 {
+		t.Errorf("unexpected prompts %v", prompts)
+	}
+
+	result := callPromptMethod(t, server, "prompts/get", MCPRequestParams{Name: "plan_meeting", Arguments: map[string]any{"timezone": "Europe/Madrid"}})["result"].(map[string]any)
+	message := result["messages"].([]any)[0].(map[string]any)
+	if message["role"] != "user" || message["content"].(map[string]any)["text"] != "Plan a meeting in Europe/Madrid" {
+		t.Errorf("unexpected prompt messages %v", result)
+	}
+
+	for _, params := range []MCPRequestParams{
+		{Name: "plan_meeting"},
+		{Name: "plan_meeting", Arguments: map[string]any{"timezone": 1}},
+		{Name: "missing"},
+	} {
+		response := callPromptMethod(t, server, "prompts/get", params)
+		if code := response["error"].(map[string]any)["code"]; code != float64(ErrInvalidParams) {
+			t.Errorf("expected invalid params for %+v, got %v", params, response)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRateLimitBuckets bounds the number of buckets kept in memory, the least recently used ones are evicted
+const maxRateLimitBuckets = 10000
+
+// RateLimit configures a token bucket: Rate tokens are refilled per second up to Burst tokens
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+type tokenBucket struct {
+	key     string
+	tokens  float64
+	last    time.Time
+	element *list.Element
+}
+
+// RateLimiter limits requests per client identity and, optionally, per tool
+type RateLimiter struct {
+	// Limit is the default limit applied to every client
+	Limit RateLimit
+	// PerTool keys tool calls by client and tool name instead of only by client
+	PerTool bool
+	// Identity resolves the client identity of a request, defaults to the client IP. Set it to key clients by an
+	// identity the server verified, e.g. the subject of a validated bearer token. Sessions are not identities, as
+	// clients open new ones at will.
+	Identity func(r *http.Request) string
+	// TrustedProxies are the networks of the proxies whose X-Forwarded-For header is trusted by the default identity
+	TrustedProxies []netip.Prefix
+
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	// order lists the buckets from the most to the least recently used
+	order *list.List
+}
+
+// NewRateLimiter creates a rate limiter applying limit to every client
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	return &RateLimiter{
+		Limit:   limit,
+		now:     time.Now,
+		buckets: map[string]*tokenBucket{},
+		order:   list.New(),
+	}
+}
+
+// RateLimitError is returned when a client exceeds its rate limit
+type RateLimitError struct {
+	Key        string
+	Tool       string
+	Limit      RateLimit
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Tool != "" {
+		return fmt.Sprintf("rate limit exceeded for tool %s, retry after %s", e.Tool, e.RetryAfter)
+	}
+	return fmt.Sprintf("rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+// RPCError converts the rate limit error to its JSON-RPC representation
+func (e *RateLimitError) RPCError() *JsonRPCError {
+	data := map[string]any{
+		"retryAfter":   int(math.Ceil(e.RetryAfter.Seconds())),
+		"retryAfterMs": e.RetryAfter.Milliseconds(),
+		"limit": map[string]any{
+			"rate":  e.Limit.Rate,
+			"burst": e.Limit.Burst,
+		},
+	}
+	if e.Tool != "" {
+		data["tool"] = e.Tool
+	}
+	return &JsonRPCError{Code: ErrRateLimited, Message: e.Error(), Data: data}
+}
+
+// Allow consumes a token for the request, returning a *RateLimitError when the client has none left.
+// tool may be nil for requests that are not tool calls.
+func (l *RateLimiter) Allow(r *http.Request, tool *ToolDescription) error {
+	limit := l.Limit
+	key := l.identity(r)
+	toolName := ""
+
+	if tool != nil && (l.PerTool || tool.RateLimit != nil) {
+		if tool.RateLimit != nil {
+			limit = *tool.RateLimit
+		}
+		toolName = tool.Name
+		key += "|tool:" + tool.Name
+	}
+
+	if !limit.enabled() {
+		return nil
+	}
+
+	retryAfter, ok := l.take(key, limit)
+	if ok {
+		return nil
+	}
+	return &RateLimitError{Key: key, Tool: toolName, Limit: limit, RetryAfter: retryAfter}
+}
+
+func (l *RateLimiter) take(key string, limit RateLimit) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = map[string]*tokenBucket{}
+	}
+	if l.order == nil {
+		l.order = list.New()
+	}
+	if l.now == nil {
+		l.now = time.Now
+	}
+	now := l.now()
+
+	bucket, ok := l.buckets[key]
+	if ok {
+		l.order.MoveToFront(bucket.element)
+	} else {
+		for len(l.buckets) >= maxRateLimitBuckets {
+			oldest := l.order.Remove(l.order.Back()).(*tokenBucket)
+			delete(l.buckets, oldest.key)
+		}
+		bucket = &tokenBucket{key: key, tokens: float64(limit.Burst), last: now}
+		bucket.element = l.order.PushFront(bucket)
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+now.Sub(bucket.last).Seconds()*limit.Rate)
+	bucket.last = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0, true
+	}
+
+	missing := 1 - bucket.tokens
+	return time.Duration(missing / limit.Rate * float64(time.Second)), false
+}
+
+// identity resolves the key of the client sending r
+func (l *RateLimiter) identity(r *http.Request) string {
+	if l.Identity != nil {
+		return l.Identity(r)
+	}
+	return clientIdentity(r, l.TrustedProxies)
+}
+
+// ClientIdentity identifies the caller of a request by IP address. Headers the client controls, such as
+// unverified tokens, session ids or X-Forwarded-For, are never used.
+func ClientIdentity(r *http.Request) string {
+	return clientIdentity(r, nil)
+}
+
+func clientIdentity(r *http.Request, trustedProxies []netip.Prefix) string {
+	return "ip:" + ClientIP(r, trustedProxies)
+}
+
+// ClientIP returns the address of the client sending r. X-Forwarded-For is only followed through proxies in
+// trustedProxies, from the closest hop backwards, so clients cannot choose the address they are keyed by.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	address := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0 && isTrustedProxy(address, trustedProxies); i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			break
+		}
+		address = hop
+	}
+	return address
+}
+
+func isTrustedProxy(address string, trustedProxies []netip.Prefix) bool {
+	ip, err := netip.ParseAddr(address)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(ip.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp/internal/hooks"
+)
+
+var (
+	// ErrToolExists is returned when registering a tool whose name is already taken
+	ErrToolExists = errors.New("tool already registered")
+	// ErrToolNotFound is returned when unregistering or replacing an unknown tool
+	ErrToolNotFound = errors.New("tool not found")
+)
+
+// ToolRegistry is a concurrency-safe set of tools indexed by name, listed in registration order.
+// The zero value is an empty registry ready to use.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]ToolDescription
+	order []string
+	// revision numbers every registered definition, so calls can tell whether their tool was replaced
+	revision uint64
+}
+
+// NewToolRegistry creates an empty registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: map[string]ToolDescription{}}
+}
+
+func validateTool(tool ToolDescription) error {
+	if tool.Name == "" {
+		return errors.New("tool name is required")
+	}
+	if tool.Handler == nil {
+		return fmt.Errorf("tool %s has no handler", tool.Name)
+	}
+	if tool.Cache != nil && tool.Cache.TTL <= 0 {
+		return fmt.Errorf("tool %s has a cache without a positive TTL", tool.Name)
+	}
+	if tool.Cache != nil && tool.Cache.PerCaller && tool.Cache.Identity == nil {
+		return fmt.Errorf("tool %s caches per caller without an Identity", tool.Name)
+	}
+	return nil
+}
+
+// Register adds tool, failing with ErrToolExists when the name is taken
+func (r *ToolRegistry) Register(tool ToolDescription) error {
+	if err := validateTool(tool); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tools == nil {
+		r.tools = map[string]ToolDescription{}
+	}
+	if _, ok := r.tools[tool.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrToolExists, tool.Name)
+	}
+	r.revision++
+	tool.revision = r.revision
+	r.tools[tool.Name] = tool
+	r.order = append(r.order, tool.Name)
+	return nil
+}
+
+// Unregister removes the tool with the given name, failing with ErrToolNotFound when missing
+func (r *ToolRegistry) Unregister(name string) error {
+	_, err := r.remove(name)
+	return err
+}
+
+// remove unregisters the tool with the given name and returns its definition
+func (r *ToolRegistry) remove(name string) (ToolDescription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, ok := r.tools[name]
+	if !ok {
+		return previous, fmt.Errorf("%w: %s", ErrToolNotFound, name)
+	}
+	delete(r.tools, name)
+	for i, existing := range r.order {
+		if existing == name {
+			r.order = append(r.order[:i:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return previous, nil
+}
+
+// Replace swaps the tool with the same name, keeping its position, failing with ErrToolNotFound when missing
+func (r *ToolRegistry) Replace(tool ToolDescription) error {
+	_, err := r.update(tool.Name, func(ToolDescription) ToolDescription { return tool })
+	return err
+}
+
+// update replaces the tool with the given name by change applied to its definition, and returns the previous
+// definition. Both happen under one lock, so concurrent changes cannot interleave.
+func (r *ToolRegistry) update(name string, change func(ToolDescription) ToolDescription) (ToolDescription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, ok := r.tools[name]
+	if !ok {
+		return previous, fmt.Errorf("%w: %s", ErrToolNotFound, name)
+	}
+	tool := change(previous)
+	if err := validateTool(tool); err != nil {
+		return previous, err
+	}
+	if tool.Name != name {
+		return previous, fmt.Errorf("tool %s cannot be renamed to %s", name, tool.Name)
+	}
+	r.revision++
+	tool.revision = r.revision
+	r.tools[name] = tool
+	return previous, nil
+}
+
+// Get returns a copy of the tool with the given name
+func (r *ToolRegistry) Get(name string) (ToolDescription, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// List returns a snapshot of the tools in registration order
+func (r *ToolRegistry) List() []ToolDescription {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tools := make([]ToolDescription, 0, len(r.order))
+	for _, name := range r.order {
+		tools = append(tools, r.tools[name])
+	}
+	return tools
+}
+
+// Len returns the number of registered tools
+func (r *ToolRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.order)
+}
+
+// RegisterTool adds a tool to the server's available tools, failing with ErrToolExists on duplicate names.
+// Tools may be registered while requests are being served.
+func (s *Server) RegisterTool(tool ToolDescription) error {
+	return s.toolsChanged(s.tools.Register(tool))
+}
+
+// UnregisterTool removes a tool from the server
+func (s *Server) UnregisterTool(name string) error {
+	previous, err := s.tools.remove(name)
+	if err != nil {
+		return err
+	}
+	invalidateTool(previous)
+	return s.toolsChanged(nil)
+}
+
+// ReplaceTool swaps an already registered tool for a new definition with the same name, dropping the
+// results the previous definition cached
+func (s *Server) ReplaceTool(tool ToolDescription) error {
+	previous, err := s.tools.update(tool.Name, func(ToolDescription) ToolDescription { return tool })
+	if err != nil {
+		return err
+	}
+	// Calls still running on the previous definition do not cache their results, see callTool
+	invalidateTool(previous)
+	return s.toolsChanged(nil)
+}
+
+func init() {
+	hooks.OverrideToolHandler = func(server any, name string, handler func(r *http.Request, params map[string]any) (any, error)) (func() error, error) {
+		return server.(*Server).overrideToolHandler(name, handler)
+	}
+}
+
+// overrideToolHandler swaps the handler of a registered tool without notifying clients or dropping its cache,
+// for mcptest. The override caches its results apart, restore brings back the previous definition.
+func (s *Server) overrideToolHandler(name string, handler func(r *http.Request, params map[string]any) (any, error)) (restore func() error, err error) {
+	original, err := s.tools.update(name, func(override ToolDescription) ToolDescription {
+		override.Handler = handler
+		if cache := override.Cache; cache != nil {
+			override.Cache = &ToolCache{TTL: cache.TTL, MaxEntries: cache.MaxEntries, PerCaller: cache.PerCaller, Identity: cache.Identity}
+		}
+		return override
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := s.tools.update(name, func(ToolDescription) ToolDescription { return original })
+		return err
+	}, nil
+}
+
+// invalidateTool drops every result cached by a tool definition that is no longer registered
+func invalidateTool(tool ToolDescription) {
+	if tool.Cache != nil {
+		prefix, _ := cacheKeyPrefix(tool.Name, nil)
+		tool.Cache.store().DeletePrefix(prefix)
+	}
+}
+
+// toolsChanged tells connected clients to refresh their tool list after a successful change
+func (s *Server) toolsChanged(err error) error {
+	if err == nil {
+		s.NotifyAll("notifications/tools/list_changed", nil)
+	}
+	return err
+}
+
+// FindTool returns a copy of the tool with the given name, or nil
+func (s *Server) FindTool(name string) *ToolDescription {
+	tool, ok := s.tools.Get(name)
+	if !ok {
+		return nil
+	}
+	return &tool
+}
+
+// Tools returns a snapshot of the registered tools in registration order
+func (s *Server) Tools() []ToolDescription {
+	return s.tools.List()
+}
+
+// isRegistered reports whether tool is still the registered definition of its name
+func (s *Server) isRegistered(tool *ToolDescription) bool {
+	current, ok := s.tools.Get(tool.Name)
+	return ok && current.revision == tool.revision
+}
@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func namedTool(name string, result any) ToolDescription {
+	return ToolDescription{
+		Name: name,
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return result, nil
+		},
+	}
+}
+
+func TestToolRegistryLifecycle(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+
+	for _, name := range []string{"a", "b", "c"} {
+		if err := server.RegisterTool(namedTool(name, name)); err != nil {
+			t.Fatalf("RegisterTool(%s) failed: %v", name, err)
+		}
+	}
+	if err := server.RegisterTool(namedTool("b", "again")); !errors.Is(err, ErrToolExists) {
+		t.Errorf("expected ErrToolExists, got %v", err)
+	}
+	if err := server.RegisterTool(ToolDescription{Name: "no_handler"}); err == nil {
+		t.Error("tools without handler should be rejected")
+	}
+
+	if err := server.ReplaceTool(namedTool("b", "replaced")); err != nil {
+		t.Fatalf("ReplaceTool failed: %v", err)
+	}
+	if err := server.ReplaceTool(namedTool("missing", nil)); !errors.Is(err, ErrToolNotFound) {
+		t.Errorf("expected ErrToolNotFound, got %v", err)
+	}
+	if err := server.UnregisterTool("a"); err != nil {
+		t.Fatalf("UnregisterTool failed: %v", err)
+	}
+	if err := server.UnregisterTool("a"); !errors.Is(err, ErrToolNotFound) {
+		t.Errorf("expected ErrToolNotFound, got %v", err)
+	}
+
+	tools := server.Tools()
+	if len(tools) != 2 || tools[0].Name != "b" || tools[1].Name != "c" {
+		t.Fatalf("unexpected tools %v", tools)
+	}
+	result, _ := server.FindTool("b").Handler(nil, nil)
+	if result != "replaced" {
+		t.Errorf("expected replaced handler, got %v", result)
+	}
+	if server.FindTool("a") != nil {
+		t.Error("unregistered tool should not be found")
+	}
+}
+
+func TestToolRegistryConcurrentMutation(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	if err := server.RegisterTool(namedTool("stable", "ok")); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("tool_%d", i)
+			for j := 0; j < 50; j++ {
+				_ = server.RegisterTool(namedTool(name, j))
+				_ = server.ReplaceTool(namedTool(name, -j))
+				_ = server.UnregisterTool(name)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				body, err := server.Handle(httptest.NewRequest("POST", "/", nil), httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: j, Method: "tools/call", Params: MCPRequestParams{Name: "stable"}})
+				if err != nil || body == nil {
+					t.Errorf("tools/call failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if tools := server.Tools(); len(tools) != 1 || tools[0].Name != "stable" {
+		t.Errorf("expected only the stable tool to remain, got %v", tools)
+	}
+}
+
+func TestUnknownToolIsInvalidParams(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	body, err := server.Handle(httptest.NewRequest("POST", "/", nil), httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: MCPRequestParams{Name: "nope"}})
+	if err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+	messages := responseMessages(t, body)
+	if rpcErr, ok := messages[0]["error"].(map[string]any); !ok || rpcErr["code"] != float64(ErrInvalidParams) {
+		t.Errorf("expected invalid params error, got %v", messages[0])
+	}
+}
@@ -0,0 +1,312 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ErrResourceNotFound is the JSON-RPC error code MCP uses for unknown resource URIs
+const ErrResourceNotFound = -32002
+
+// ErrResourceExists is returned when registering a resource whose URI is already taken
+var ErrResourceExists = errors.New("resource already registered")
+
+// Resource is a piece of data exposed by the server under a URI
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+
+	Handler func(r *http.Request, uri string) ([]ResourceContents, error) `json:"-"`
+}
+
+// ResourceTemplate exposes a family of resources whose URIs match a template such as "hour://{timezone}".
+// Each {variable} matches one or more characters and is passed to the handler unescaped.
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+
+	Handler func(r *http.Request, uri string, variables map[string]string) ([]ResourceContents, error) `json:"-"`
+
+	pattern   *regexp.Regexp
+	variables []string
+}
+
+// ResourceContents is the content of a resource, either Text or base64 encoded Blob
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// RegisterResource adds a resource to the server, failing with ErrResourceExists on duplicate URIs
+func (s *Server) RegisterResource(resource Resource) error {
+	if resource.URI == "" {
+		return errors.New("resource uri is required")
+	}
+	if resource.Handler == nil {
+		return fmt.Errorf("resource %s has no handler", resource.URI)
+	}
+
+	s.resourcesMu.Lock()
+	if _, ok := s.resources[resource.URI]; ok {
+		s.resourcesMu.Unlock()
+		return fmt.Errorf("%w: %s", ErrResourceExists, resource.URI)
+	}
+	if s.resources == nil {
+		s.resources = map[string]Resource{}
+	}
+	s.resources[resource.URI] = resource
+	s.resourceOrder = append(s.resourceOrder, resource.URI)
+	s.resourcesMu.Unlock()
+
+	s.NotifyAll("notifications/resources/list_changed", nil)
+	return nil
+}
+
+// UnregisterResource removes a resource from the server
+func (s *Server) UnregisterResource(uri string) error {
+	s.resourcesMu.Lock()
+	if _, ok := s.resources[uri]; !ok {
+		s.resourcesMu.Unlock()
+		return fmt.Errorf("resource not found: %s", uri)
+	}
+	delete(s.resources, uri)
+	for i, existing := range s.resourceOrder {
+		if existing == uri {
+			s.resourceOrder = append(s.resourceOrder[:i:i], s.resourceOrder[i+1:]...)
+			break
+		}
+	}
+	s.resourcesMu.Unlock()
+
+	s.NotifyAll("notifications/resources/list_changed", nil)
+	return nil
+}
+
+// FindResource returns the resource registered under uri, nil when missing
+func (s *Server) FindResource(uri string) *Resource {
+	s.resourcesMu.RLock()
+	defer s.resourcesMu.RUnlock()
+	if resource, ok := s.resources[uri]; ok {
+		return &resource
+	}
+	return nil
+}
+
+// Resources returns a snapshot of the registered resources in registration order
+func (s *Server) Resources() []Resource {
+	s.resourcesMu.RLock()
+	defer s.resourcesMu.RUnlock()
+	resources := make([]Resource, 0, len(s.resourceOrder))
+	for _, uri := range s.resourceOrder {
+		resources = append(resources, s.resources[uri])
+	}
+	return resources
+}
+
+// PublishResourceUpdated tells the clients subscribed to uri that its content changed.
+// Notifications are delivered on the open streams of the subscribed sessions, including the sessions of the
+// gateways this server is mounted on.
+func (s *Server) PublishResourceUpdated(uri string) {
+	for _, session := range s.Sessions() {
+		if !session.IsSubscribed(uri) || !session.HasOpenStream() {
+			continue
+		}
+		if err := session.Notify("notifications/resources/updated", map[string]any{"uri": uri}); err != nil {
+			s.logger().Warn("Failed to notify resource update", "session", session.ID, "uri", uri, "error", err)
+		}
+	}
+
+	s.gatewaysMu.Lock()
+	gateways := append([]*Server(nil), s.gateways...)
+	s.gatewaysMu.Unlock()
+	for _, gateway := range gateways {
+		gateway.PublishResourceUpdated(uri)
+	}
+}
+
+// Subscribe registers the interest of the client in updates of uri
+func (s *Session) Subscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscriptions == nil {
+		s.subscriptions = map[string]struct{}{}
+	}
+	s.subscriptions[uri] = struct{}{}
+}
+
+// Unsubscribe drops the subscription of the client to uri
+func (s *Session) Unsubscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, uri)
+}
+
+// IsSubscribed reports whether the client subscribed to updates of uri
+func (s *Session) IsSubscribed(uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.subscriptions[uri]
+	return ok
+}
+
+// handleReadResource implements resources/read, resources registered under uri win over matching templates
+func (s *Server) handleReadResource(r *http.Request, uri string) (any, error) {
+	var contents []ResourceContents
+	var mimeType string
+	var err error
+	if resource := s.FindResource(uri); resource != nil {
+		mimeType = resource.MimeType
+		contents, err = resource.Handler(r, uri)
+	} else if template, variables := s.matchResourceTemplate(uri); template != nil {
+		mimeType = template.MimeType
+		contents, err = template.Handler(r, uri, variables)
+	} else {
+		return nil, &JsonRPCError{Code: ErrResourceNotFound, Message: "resource not found", Data: map[string]any{"uri": uri}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range contents {
+		if contents[i].URI == "" {
+			contents[i].URI = uri
+		}
+		if contents[i].MimeType == "" {
+			contents[i].MimeType = mimeType
+		}
+	}
+	return map[string]any{"contents": contents}, nil
+}
+
+var templateVariable = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// RegisterResourceTemplate adds a resource template to the server, failing with ErrResourceExists on duplicates
+func (s *Server) RegisterResourceTemplate(template ResourceTemplate) error {
+	if template.URITemplate == "" {
+		return errors.New("resource template uri is required")
+	}
+	if template.Handler == nil {
+		return fmt.Errorf("resource template %s has no handler", template.URITemplate)
+	}
+	template.pattern, template.variables = compileURITemplate(template.URITemplate)
+
+	s.resourcesMu.Lock()
+	if _, ok := s.resourceTemplates[template.URITemplate]; ok {
+		s.resourcesMu.Unlock()
+		return fmt.Errorf("%w: %s", ErrResourceExists, template.URITemplate)
+	}
+	if s.resourceTemplates == nil {
+		s.resourceTemplates = map[string]ResourceTemplate{}
+	}
+	s.resourceTemplates[template.URITemplate] = template
+	s.templateOrder = append(s.templateOrder, template.URITemplate)
+	s.resourcesMu.Unlock()
+
+	s.NotifyAll("notifications/resources/list_changed", nil)
+	return nil
+}
+
+// UnregisterResourceTemplate removes a resource template from the server
+func (s *Server) UnregisterResourceTemplate(uriTemplate string) error {
+	s.resourcesMu.Lock()
+	if _, ok := s.resourceTemplates[uriTemplate]; !ok {
+		s.resourcesMu.Unlock()
+		return fmt.Errorf("resource template not found: %s", uriTemplate)
+	}
+	delete(s.resourceTemplates, uriTemplate)
+	for i, existing := range s.templateOrder {
+		if existing == uriTemplate {
+			s.templateOrder = append(s.templateOrder[:i:i], s.templateOrder[i+1:]...)
+			break
+		}
+	}
+	s.resourcesMu.Unlock()
+
+	s.NotifyAll("notifications/resources/list_changed", nil)
+	return nil
+}
+
+// ResourceTemplates returns a snapshot of the registered resource templates in registration order
+func (s *Server) ResourceTemplates() []ResourceTemplate {
+	s.resourcesMu.RLock()
+	defer s.resourcesMu.RUnlock()
+	templates := make([]ResourceTemplate, 0, len(s.templateOrder))
+	for _, uriTemplate := range s.templateOrder {
+		templates = append(templates, s.resourceTemplates[uriTemplate])
+	}
+	return templates
+}
+
+// FindResourceTemplate returns the template registered as uriTemplate, nil when missing
+func (s *Server) FindResourceTemplate(uriTemplate string) *ResourceTemplate {
+	s.resourcesMu.RLock()
+	defer s.resourcesMu.RUnlock()
+	if template, ok := s.resourceTemplates[uriTemplate]; ok {
+		return &template
+	}
+	return nil
+}
+
+// matchResourceTemplate returns the first template matching uri with the values of its variables
+func (s *Server) matchResourceTemplate(uri string) (*ResourceTemplate, map[string]string) {
+	for _, template := range s.ResourceTemplates() {
+		match := template.pattern.FindStringSubmatch(uri)
+		if match == nil {
+			continue
+		}
+		variables := make(map[string]string, len(template.variables))
+		for i, name := range template.variables {
+			value, err := url.PathUnescape(match[i+1])
+			if err != nil {
+				value = match[i+1]
+			}
+			variables[name] = value
+		}
+		return &template, variables
+	}
+	return nil, nil
+}
+
+// compileURITemplate turns the {variables} of a URI template into capture groups
+func compileURITemplate(uriTemplate string) (*regexp.Regexp, []string) {
+	var pattern strings.Builder
+	var variables []string
+	last := 0
+	for _, match := range templateVariable.FindAllStringSubmatchIndex(uriTemplate, -1) {
+		pattern.WriteString(regexp.QuoteMeta(uriTemplate[last:match[0]]))
+		pattern.WriteString("(.+?)")
+		variables = append(variables, uriTemplate[match[2]:match[3]])
+		last = match[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(uriTemplate[last:]))
+	return regexp.MustCompile("^" + pattern.String() + "$"), variables
+}
+
+// handleSubscription implements resources/subscribe and resources/unsubscribe
+func (s *Server) handleSubscription(session *Session, uri string, subscribe bool) (any, error) {
+	if !s.Streaming {
+		return nil, &JsonRPCError{Code: ErrInvalidRequest, Message: "resource subscriptions require a streaming server"}
+	}
+	if session == nil {
+		return nil, &JsonRPCError{Code: ErrInvalidRequest, Message: "resource subscriptions require an initialized session"}
+	}
+	if subscribe {
+		if s.FindResource(uri) == nil {
+			return nil, &JsonRPCError{Code: ErrResourceNotFound, Message: "resource not found", Data: map[string]any{"uri": uri}}
+		}
+		session.Subscribe(uri)
+	} else {
+		session.Unsubscribe(uri)
+	}
+	return map[string]any{}, nil
+}
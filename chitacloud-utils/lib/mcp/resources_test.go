@@ -0,0 +1,135 @@
+package mcp
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func statusResource(status *string) Resource {
+	return Resource{
+		URI:      "push://deliveries/42",
+		Name:     "delivery 42",
+		MimeType: "application/json",
+		Handler: func(r *http.Request, uri string) ([]ResourceContents, error) {
+			return []ResourceContents{{Text: `{"status":"` + *status + `"}`}}, nil
+		},
+	}
+}
+
+func hourTemplate() ResourceTemplate {
+	return ResourceTemplate{
+		URITemplate: "hour://{timezone}",
+		Name:        "current hour",
+		MimeType:    "text/plain",
+		Handler: func(r *http.Request, uri string, variables map[string]string) ([]ResourceContents, error) {
+			return []ResourceContents{{Text: "hour in " + variables["timezone"]}}, nil
+		},
+	}
+}
+
+// callResourceMethod sends a resources/* request within a session and returns the response message
+func callResourceMethod(t *testing.T, server *Server, sessionID string, method string, uri string) map[string]any {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(SessionHeader, sessionID)
+	body, err := server.Handle(r, httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: 9, Method: method, Params: MCPRequestParams{URI: uri}})
+	if err != nil {
+		t.Fatalf("%s failed: %v", method, err)
+	}
+	defer body.Close()
+	return responseMessages(t, body)[0]
+}
+
+func TestReadResource(t *testing.T) {
+	status := "queued"
+	server := NewServer("test", "1.0", "test")
+	if err := server.RegisterResource(statusResource(&status)); err != nil {
+		t.Fatal(err)
+	}
+	sessionID := initializeSession(t, server, nil)
+
+	contents := callResourceMethod(t, server, sessionID, "resources/read", "push://deliveries/42")["result"].(map[string]any)["contents"].([]any)
+	content := contents[0].(map[string]any)
+	if content["uri"] != "push://deliveries/42" || content["mimeType"] != "application/json" || content["text"] != `{"status":"queued"}` {
+		t.Errorf("unexpected contents %v", content)
+	}
+
+	response := callResourceMethod(t, server, sessionID, "resources/read", "push://deliveries/missing")
+	if code := response["error"].(map[string]any)["code"]; code != float64(ErrResourceNotFound) {
+		t.Errorf("expected a resource not found error, got %v", response)
+	}
+}
+
+func TestResourceSubscriptionDeliversUpdates(t *testing.T) {
+	status := "queued"
+	server := NewServer("test", "1.0", "test")
+	server.SetStreaming(true)
+	server.RegisterResource(statusResource(&status))
+
+	subscribed := initializeSession(t, server, nil)
+	other := initializeSession(t, server, nil)
+	stream := openTestStream(t, server, subscribed)
+	defer stream.Close()
+	otherStream := openTestStream(t, server, other)
+	defer otherStream.Close()
+
+	if response := callResourceMethod(t, server, subscribed, "resources/subscribe", "push://deliveries/42"); response["error"] != nil {
+		t.Fatalf("subscribe failed: %v", response)
+	}
+
+	status = "delivered"
+	server.PublishResourceUpdated("push://deliveries/42")
+
+	message := readStreamMessage(t, bufio.NewReader(stream))
+	if message["method"] != "notifications/resources/updated" || message["params"].(map[string]any)["uri"] != "push://deliveries/42" {
+		t.Errorf("unexpected notification %v", message)
+	}
+	if server.Session(other).IsSubscribed("push://deliveries/42") {
+		t.Error("only the subscribing session should be subscribed")
+	}
+
+	callResourceMethod(t, server, subscribed, "resources/unsubscribe", "push://deliveries/42")
+	if server.Session(subscribed).IsSubscribed("push://deliveries/42") {
+		t.Error("unsubscribe should drop the subscription")
+	}
+}
+
+func TestSubscribeRequiresStreaming(t *testing.T) {
+	status := "queued"
+	server := NewServer("test", "1.0", "test")
+	server.RegisterResource(statusResource(&status))
+	sessionID := initializeSession(t, server, nil)
+
+	if response := callResourceMethod(t, server, sessionID, "resources/subscribe", "push://deliveries/42"); response["error"] == nil {
+		t.Errorf("expected subscriptions to fail without streaming, got %v", response)
+	}
+}
+
+func TestReadResourceTemplate(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	if err := server.RegisterResourceTemplate(hourTemplate()); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterResourceTemplate(hourTemplate()); !errors.Is(err, ErrResourceExists) {
+		t.Errorf("expected ErrResourceExists, got %v", err)
+	}
+	sessionID := initializeSession(t, server, nil)
+
+	templates := callResourceMethod(t, server, sessionID, "resources/templates/list", "")["result"].(map[string]any)["resourceTemplates"].([]any)
+	if len(templates) != 1 || templates[0].(map[string]any)["uriTemplate"] != "hour://{timezone}" {
+		t.Fatalf("unexpected templates %v", templates)
+	}
+
+	for uri, expected := range map[string]string{"hour://Europe/Madrid": "hour in Europe/Madrid", "hour://America%2FNew_York": "hour in America/New_York"} {
+		contents := callResourceMethod(t, server, sessionID, "resources/read", uri)["result"].(map[string]any)["contents"].([]any)
+		if content := contents[0].(map[string]any); content["text"] != expected || content["uri"] != uri || content["mimeType"] != "text/plain" {
+			t.Errorf("unexpected contents of %s: %v", uri, content)
+		}
+	}
+	if response := callResourceMethod(t, server, sessionID, "resources/read", "hour://"); response["error"] == nil {
+		t.Errorf("expected empty variables not to match, got %v", response)
+	}
+}
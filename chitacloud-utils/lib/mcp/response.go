@@ -3,10 +3,17 @@ package mcp
 
 import (
 	"encoding/json"
+	"errors"
 )
 
 const (
-	ErrUnkown = -32001
+	ErrParse          = -32700
+	ErrInvalidRequest = -32600
+	ErrMethodNotFound = -32601
+	ErrInvalidParams  = -32602
+	ErrInternal       = -32603
+	ErrUnkown         = -32001
+	ErrRateLimited    = -32029
 )
 
 /**
@@ -44,6 +51,23 @@ type JsonRPCError struct {
 	Data    any    `json:"data,omitempty"`
 }
 
+func (e *JsonRPCError) Error() string {
+	return e.Message
+}
+
+// rpcError converts err to the JSON-RPC error sent to the client, keeping codes of typed errors
+func rpcError(err error, content any) JsonRPCError {
+	var rpcErr *JsonRPCError
+	if errors.As(err, &rpcErr) {
+		return *rpcErr
+	}
+	var converter interface{ RPCError() *JsonRPCError }
+	if errors.As(err, &converter) {
+		return *converter.RPCError()
+	}
+	return JsonRPCError{Code: ErrUnkown, Message: err.Error(), Data: map[string]any{"content": content}}
+}
+
 type ProgressInfo struct {
 	ProgressToken any `json:"progressToken"`
 	Progress      int `json:"progress"`
@@ -56,7 +80,8 @@ func FormatMCPServerResponse(id int, method string, streamId string, content any
 		"jsonrpc": "2.0",
 	}
 	if err != nil {
-		responseObj["error"] = JsonRPCError{Code: ErrUnkown, Message: err.Error(), Data: map[string]any{"content": content}}
+		responseObj["id"] = id
+		responseObj["error"] = rpcError(err, content)
 	} else {
 		if method == "notifications/progress" && progressInfo != nil {
 			responseObj["method"] = method
@@ -0,0 +1,220 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// EnableREST exposes every tool as POST {basePath}/tools/{name} with a JSON body of arguments, and as GET with
+// query parameters for ReadOnly tools whose arguments are all primitives. Responses are the plain JSON tool results.
+func (s *Server) EnableREST(basePath string) {
+	s.RESTPrefix = strings.TrimSuffix(basePath, "/") + ToolsPathPrefix
+}
+
+// restToolName returns the tool addressed by a REST request
+func (s *Server) restToolName(r *http.Request) (string, bool) {
+	if s.RESTPrefix == "" || r.URL == nil || (r.Method != http.MethodPost && r.Method != http.MethodGet) {
+		return "", false
+	}
+	name, ok := strings.CutPrefix(r.URL.Path, s.RESTPrefix)
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// isRESTRequest reports whether r calls a tool through its REST endpoint
+func (s *Server) isRESTRequest(r *http.Request) bool {
+	_, ok := s.restToolName(r)
+	return ok
+}
+
+// serveREST runs a tool for a REST request, going through the same rate limits, spans and metrics as tools/call
+func (s *Server) serveREST(r *http.Request, w http.ResponseWriter, req MCPRequest, name string, start time.Time) (io.ReadCloser, error) {
+	logger := LoggerFromContext(r.Context()).With("tool", name)
+	r = r.WithContext(ContextWithLogger(r.Context(), logger))
+	s.corsPolicy().Apply(w, r.Header.Get("Origin"))
+
+	if err := s.checkRequestSize(r, req); err != nil {
+		logger.Warn("Rejected oversized request", "error", err)
+		return writeREST(w, http.StatusRequestEntityTooLarge, rpcError(err, nil))
+	}
+
+	tool := s.FindTool(name)
+	if tool == nil {
+		logger.Warn("Tool not found")
+		return restResponse(w, nil, &JsonRPCError{Code: ErrMethodNotFound, Message: fmt.Sprintf("%s: %s", ErrToolNotFound, name)})
+	}
+
+	var arguments map[string]any
+	var err error
+	if r.Method == http.MethodGet {
+		if !allowsGET(tool) {
+			w.Header().Set("Allow", http.MethodPost)
+			return writeREST(w, http.StatusMethodNotAllowed, &JsonRPCError{Code: ErrInvalidRequest, Message: "tool " + name + " must be called with POST"})
+		}
+		arguments = queryArguments(r.URL.Query(), tool.InputSchema)
+	} else {
+		arguments, err = bodyArguments(r, req)
+		if err != nil {
+			return restResponse(w, nil, err)
+		}
+	}
+
+	logger.Debug("REST tool call", "arguments", s.RedactArguments(arguments))
+	call := MCPRequest{JSONRPC: "2.0", Method: "tools/call", Params: MCPRequestParams{Name: name, Arguments: arguments}}
+	if err := s.checkRateLimit(r, w, call); err != nil {
+		s.observeRequest("rest", err, start)
+		return restResponse(w, nil, err)
+	}
+
+	remote, _ := extractTraceContext(r, call.Params)
+	ctx, span := s.startSpan(r.Context(), "rest "+name, SpanKindServer, remote)
+	span.SetAttribute("http.request.method", r.Method)
+	span.SetAttribute("url.path", r.URL.Path)
+	defer span.End()
+
+	result, _, err := s.callTool(r.WithContext(ctx), name, arguments)
+	span.RecordError(err)
+	s.observeRequest("rest", err, start)
+	return restResponse(w, result, err)
+}
+
+// bodyArguments decodes the JSON object of arguments of a POST request, an empty body means no arguments
+func bodyArguments(r *http.Request, req MCPRequest) (map[string]any, error) {
+	payload := req.LambdaRequest.Payload
+	if len(payload) == 0 && r.Body != nil {
+		var err error
+		if payload, err = io.ReadAll(r.Body); err != nil {
+			return nil, &JsonRPCError{Code: ErrParse, Message: "failed to read request body: " + err.Error()}
+		}
+	}
+	arguments := map[string]any{}
+	if len(bytes.TrimSpace(payload)) == 0 {
+		return arguments, nil
+	}
+	if err := json.Unmarshal(payload, &arguments); err != nil {
+		return nil, &JsonRPCError{Code: ErrParse, Message: "arguments must be a JSON object: " + err.Error()}
+	}
+	return arguments, nil
+}
+
+// allowsGET reports whether tool can be called with GET. Cross-site links and images send GET requests without
+// an Origin header, so only tools declared ReadOnly are exposed that way.
+func allowsGET(tool *ToolDescription) bool {
+	return tool.ReadOnly && isQueryableSchema(tool.InputSchema)
+}
+
+// isQueryableSchema reports whether the arguments described by schema fit in query parameters
+func isQueryableSchema(schema *openapi3.Schema) bool {
+	if schema == nil {
+		return true
+	}
+	for _, property := range schema.Properties {
+		if property == nil || property.Value == nil {
+			return false
+		}
+		value := property.Value
+		if value.Type != nil && value.Type.Is(openapi3.TypeArray) {
+			if value.Items == nil || value.Items.Value == nil || !isPrimitiveSchema(value.Items.Value) {
+				return false
+			}
+		} else if !isPrimitiveSchema(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func isPrimitiveSchema(schema *openapi3.Schema) bool {
+	types := schema.Type
+	return types != nil && (types.Is(openapi3.TypeString) || types.Is(openapi3.TypeNumber) || types.Is(openapi3.TypeInteger) || types.Is(openapi3.TypeBoolean))
+}
+
+// queryArguments converts query parameters to arguments using the property types of schema. Numbers are
+// decoded as float64, like the arguments of JSON bodies; values that do not parse are passed as strings.
+func queryArguments(query url.Values, schema *openapi3.Schema) map[string]any {
+	arguments := make(map[string]any, len(query))
+	for name, values := range query {
+		if len(values) == 0 {
+			continue
+		}
+		var property *openapi3.Schema
+		if schema != nil && schema.Properties[name] != nil {
+			property = schema.Properties[name].Value
+		}
+		if property != nil && property.Type != nil && property.Type.Is(openapi3.TypeArray) {
+			var items *openapi3.Schema
+			if property.Items != nil {
+				items = property.Items.Value
+			}
+			converted := make([]any, 0, len(values))
+			for _, value := range values {
+				converted = append(converted, queryValue(value, items))
+			}
+			arguments[name] = converted
+			continue
+		}
+		arguments[name] = queryValue(values[0], property)
+	}
+	return arguments
+}
+
+func queryValue(value string, schema *openapi3.Schema) any {
+	if schema == nil || schema.Type == nil {
+		return value
+	}
+	switch {
+	case schema.Type.Is(openapi3.TypeNumber), schema.Type.Is(openapi3.TypeInteger):
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case schema.Type.Is(openapi3.TypeBoolean):
+		if boolean, err := strconv.ParseBool(value); err == nil {
+			return boolean
+		}
+	}
+	return value
+}
+
+// restResponse writes the plain JSON result, or the JSON-RPC error object with a matching HTTP status
+func restResponse(w http.ResponseWriter, result any, err error) (io.ReadCloser, error) {
+	if err != nil {
+		rpcErr := rpcError(err, nil)
+		return writeREST(w, restStatus(rpcErr.Code), rpcErr)
+	}
+	return writeREST(w, http.StatusOK, result)
+}
+
+func writeREST(w http.ResponseWriter, status int, payload any) (io.ReadCloser, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal REST response: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return io.NopCloser(bytes.NewReader(body)), nil
+}
+
+// restStatus maps JSON-RPC error codes to HTTP statuses
+func restStatus(code int) int {
+	switch code {
+	case ErrParse, ErrInvalidRequest, ErrInvalidParams:
+		return http.StatusBadRequest
+	case ErrMethodNotFound, ErrResourceNotFound:
+		return http.StatusNotFound
+	case ErrRateLimited:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
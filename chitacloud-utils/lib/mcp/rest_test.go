@@ -0,0 +1,160 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func restTestServer() *Server {
+	server := NewServer("test", "1.0", "test")
+	server.EnableREST("/api/")
+	server.RegisterTool(ToolDescription{
+		Name:     "add",
+		ReadOnly: true,
+		InputSchema: openapi3.NewObjectSchema().
+			WithProperty("a", openapi3.NewFloat64Schema()).
+			WithProperty("b", openapi3.NewFloat64Schema()).
+			WithProperty("tags", openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema())),
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			a, _ := params["a"].(float64)
+			b, _ := params["b"].(float64)
+			return map[string]any{"sum": a + b, "tags": params["tags"]}, nil
+		},
+	})
+	server.RegisterTool(ToolDescription{
+		Name:        "create",
+		InputSchema: openapi3.NewObjectSchema().WithProperty("user", openapi3.NewObjectSchema()),
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			if params["user"] == nil {
+				return nil, &JsonRPCError{Code: ErrInvalidParams, Message: "user is required"}
+			}
+			return []string{"created"}, nil
+		},
+	})
+	server.RegisterTool(ToolDescription{
+		Name: "fail",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			return nil, errors.New("boom")
+		},
+	})
+	return server
+}
+
+func serveRESTRequest(t *testing.T, server *Server, method, target, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	var response map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil && w.Body.String() != "null" && !strings.HasPrefix(w.Body.String(), "[") {
+		t.Fatalf("invalid JSON response %q: %v", w.Body.String(), err)
+	}
+	return w, response
+}
+
+func TestRESTPostCallsTool(t *testing.T) {
+	server := restTestServer()
+
+	w, response := serveRESTRequest(t, server, http.MethodPost, "/api/tools/add", `{"a": 1, "b": 2.5, "id": "not-a-json-rpc-id"}`)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if response["sum"] != 3.5 {
+		t.Errorf("expected the plain tool result, got %v", response)
+	}
+
+	w, _ = serveRESTRequest(t, server, http.MethodPost, "/api/tools/create", `{"user": {"name": "ada"}}`)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `["created"]` {
+		t.Errorf("expected slices to be returned as JSON arrays, got %q", w.Body.String())
+	}
+}
+
+func TestRESTGetUsesQueryParameters(t *testing.T) {
+	server := restTestServer()
+
+	w, response := serveRESTRequest(t, server, http.MethodGet, "/api/tools/add?a=2&b=3&tags=x&tags=y", "")
+	if w.Code != http.StatusOK || response["sum"] != float64(5) {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if tags, _ := response["tags"].([]any); len(tags) != 2 {
+		t.Errorf("expected repeated parameters as an array, got %v", response["tags"])
+	}
+
+	w, _ = serveRESTRequest(t, server, http.MethodGet, "/api/tools/create", "")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("expected GET to be refused for structured arguments, got %d", w.Code)
+	}
+
+	// Tools with side effects are never reachable through GET, even with primitive arguments
+	server.RegisterTool(ToolDescription{
+		Name:        "delete",
+		InputSchema: openapi3.NewObjectSchema().WithProperty("id", openapi3.NewStringSchema()),
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			t.Error("delete should not run on GET")
+			return nil, nil
+		},
+	})
+	w, _ = serveRESTRequest(t, server, http.MethodGet, "/api/tools/delete?id=1", "")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected GET to be refused for tools that are not read-only, got %d", w.Code)
+	}
+	if item := server.OpenAPI().Paths.Find("/api/tools/delete"); item == nil || item.Get != nil {
+		t.Errorf("expected only a POST operation for delete, got %+v", item)
+	}
+}
+
+func TestRESTErrors(t *testing.T) {
+	server := restTestServer()
+	for _, test := range []struct {
+		name   string
+		target string
+		body   string
+		status int
+		code   int
+	}{
+		{"unknown tool", "/api/tools/missing", `{}`, http.StatusNotFound, ErrMethodNotFound},
+		{"invalid body", "/api/tools/add", `[1, 2]`, http.StatusBadRequest, ErrParse},
+		{"invalid params", "/api/tools/create", ``, http.StatusBadRequest, ErrInvalidParams},
+		{"tool failure", "/api/tools/fail", ``, http.StatusInternalServerError, ErrUnkown},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w, response := serveRESTRequest(t, server, http.MethodPost, test.target, test.body)
+			if w.Code != test.status || response["code"] != float64(test.code) {
+				t.Errorf("expected %d with code %d, got %d %q", test.status, test.code, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRESTIsDisabledByDefault(t *testing.T) {
+	server := restTestServer()
+	server.RESTPrefix = ""
+	r := httptest.NewRequest(http.MethodPost, "/api/tools/add", nil)
+	w := httptest.NewRecorder()
+	body, err := server.Handle(r, w, MCPRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _ := io.ReadAll(body)
+	if strings.Contains(string(payload), "sum") {
+		t.Errorf("expected no REST routing, got %q", payload)
+	}
+}
+
+func TestRESTOperationsInOpenAPI(t *testing.T) {
+	doc := restTestServer().OpenAPI()
+	add := doc.Paths.Find("/api/tools/add")
+	if add == nil || add.Post == nil || add.Get == nil || len(add.Get.Parameters) != 3 {
+		t.Fatalf("expected POST and GET operations for add, got %+v", add)
+	}
+	if create := doc.Paths.Find("/api/tools/create"); create == nil || create.Get != nil {
+		t.Errorf("expected only a POST operation for create, got %+v", create)
+	}
+}
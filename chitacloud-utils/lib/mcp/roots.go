@@ -0,0 +1,78 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"context"
+)
+
+// Root is a filesystem or URI location the client allows the server to operate on
+type Root struct {
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+// Roots returns the roots exposed by the client of the current request.
+// They are requested with roots/list the first time and cached in the session until the client
+// sends notifications/roots/list_changed. It fails with ErrCapabilityNotSupported when the client
+// did not declare the roots capability.
+func Roots(ctx context.Context) ([]Root, error) {
+	session, err := sessionWithCapability(ctx, "roots")
+	if err != nil {
+		return nil, err
+	}
+	if roots, ok := session.Roots(); ok {
+		return roots, nil
+	}
+	return session.refreshRoots(ctx)
+}
+
+// Roots returns the cached roots of the client, reporting whether they were loaded
+func (s *Session) Roots() ([]Root, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Root(nil), s.roots...), s.rootsLoaded
+}
+
+// refreshRoots requests roots/list from the client and caches the result, unless the roots changed again
+// while the request was running
+func (s *Session) refreshRoots(ctx context.Context) ([]Root, error) {
+	s.mu.Lock()
+	generation := s.rootsGeneration
+	s.mu.Unlock()
+
+	var result struct {
+		Roots []Root `json:"roots"`
+	}
+	if err := s.Request(ctx, "roots/list", nil, &result); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.rootsGeneration == generation {
+		s.roots = result.Roots
+		s.rootsLoaded = true
+	}
+	s.mu.Unlock()
+	return append([]Root(nil), result.Roots...), nil
+}
+
+// rootsChanged drops the cached roots of session and reloads them when the client keeps a stream open
+func (s *Server) rootsChanged(session *Session) {
+	session.mu.Lock()
+	session.roots = nil
+	session.rootsLoaded = false
+	session.rootsGeneration++
+	session.mu.Unlock()
+
+	if !session.HasCapability("roots") || !session.HasOpenStream() {
+		// Reloaded on the next call to Roots
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultServerRequestTimeout)
+		defer cancel()
+		if _, err := session.refreshRoots(ctx); err != nil {
+			s.logger().Warn("Failed to refresh client roots", "session", session.ID, "error", err)
+		}
+	}()
+}
@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// postClientNotification sends a client notification and returns the HTTP status
+func postClientNotification(t *testing.T, server *Server, sessionID string, method string) int {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(SessionHeader, sessionID)
+	w := httptest.NewRecorder()
+	body, err := server.Handle(r, w, MCPRequest{JSONRPC: "2.0", Method: method})
+	if err != nil {
+		t.Fatalf("posting notification failed: %v", err)
+	}
+	body.Close()
+	return w.Code
+}
+
+func TestRootsAreCachedPerSession(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetStreaming(true)
+	server.RegisterTool(ToolDescription{
+		Name: "list_roots",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			roots, err := Roots(r.Context())
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"first": roots[0].URI, "count": len(roots)}, nil
+		},
+	})
+
+	sessionID := initializeSession(t, server, map[string]any{"roots": map[string]any{"listChanged": true}})
+	reader := callToolStreaming(t, server, sessionID, 2, "list_roots")
+
+	request := readStreamMessage(t, reader)
+	if request["method"] != "roots/list" {
+		t.Fatalf("expected a roots/list request, got %v", request)
+	}
+	postClientResponse(t, server, sessionID, int(request["id"].(float64)), map[string]any{
+		"roots": []Root{{URI: "file:///workspace", Name: "workspace"}, {URI: "file:///tmp"}},
+	})
+	if response := readStreamMessage(t, reader); response["result"].(map[string]any)["structuredContent"].(map[string]any)["first"] != "file:///workspace" {
+		t.Fatalf("unexpected tool result %v", response)
+	}
+
+	// The second call is answered from the session cache without asking the client
+	reader = callToolStreaming(t, server, sessionID, 3, "list_roots")
+	response := readStreamMessage(t, reader)
+	if response["id"] != float64(3) || response["result"].(map[string]any)["structuredContent"].(map[string]any)["count"] != float64(2) {
+		t.Fatalf("expected a cached answer, got %v", response)
+	}
+}
+
+func TestRootsListChangedRefreshesOverStream(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetStreaming(true)
+
+	sessionID := initializeSession(t, server, map[string]any{"roots": map[string]any{"listChanged": true}})
+	stream := openTestStream(t, server, sessionID)
+	defer stream.Close()
+	reader := bufio.NewReader(stream)
+
+	if code := postClientNotification(t, server, sessionID, "notifications/roots/list_changed"); code != http.StatusAccepted {
+		t.Fatalf("expected 202 for a notification, got %d", code)
+	}
+
+	request := readStreamMessage(t, reader)
+	if request["method"] != "roots/list" {
+		t.Fatalf("expected a roots/list request, got %v", request)
+	}
+	postClientResponse(t, server, sessionID, int(request["id"].(float64)), map[string]any{
+		"roots": []Root{{URI: "file:///projects/new"}},
+	})
+
+	session := server.Session(sessionID)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if roots, ok := session.Roots(); ok {
+			if len(roots) != 1 || roots[0].URI != "file:///projects/new" {
+				t.Errorf("unexpected roots %v", roots)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("roots were not refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStaleRootsRefreshIsDropped(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetStreaming(true)
+
+	sessionID := initializeSession(t, server, map[string]any{"roots": map[string]any{"listChanged": true}})
+	stream := openTestStream(t, server, sessionID)
+	defer stream.Close()
+	reader := bufio.NewReader(stream)
+
+	postClientNotification(t, server, sessionID, "notifications/roots/list_changed")
+	stale := readStreamMessage(t, reader)
+	postClientNotification(t, server, sessionID, "notifications/roots/list_changed")
+	fresh := readStreamMessage(t, reader)
+
+	// The answer to the first refresh arrives after the roots changed again and must not be cached
+	postClientResponse(t, server, sessionID, int(stale["id"].(float64)), map[string]any{"roots": []Root{{URI: "file:///projects/old"}}})
+	postClientResponse(t, server, sessionID, int(fresh["id"].(float64)), map[string]any{"roots": []Root{{URI: "file:///projects/new"}}})
+
+	session := server.Session(sessionID)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if roots, ok := session.Roots(); ok {
+			if len(roots) != 1 || roots[0].URI != "file:///projects/new" {
+				t.Errorf("expected the roots of the last refresh, got %v", roots)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("roots were not refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRootsRequireCapability(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	sessionID := initializeSession(t, server, nil)
+
+	if code := postClientNotification(t, server, sessionID, "notifications/roots/list_changed"); code != http.StatusAccepted {
+		t.Errorf("expected 202 for a notification, got %d", code)
+	}
+	if _, ok := server.Session(sessionID).Roots(); ok {
+		t.Error("expected no roots to be loaded")
+	}
+}
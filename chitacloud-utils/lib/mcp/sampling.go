@@ -0,0 +1,83 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"context"
+	"errors"
+)
+
+// SamplingContent is a text, image or audio content block of a sampling message
+type SamplingContent struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// SamplingMessage is a message of the conversation sent to the client's model
+type SamplingMessage struct {
+	Role    string          `json:"role"`
+	Content SamplingContent `json:"content"`
+}
+
+// ModelHint suggests a model name, matched loosely by the client
+type ModelHint struct {
+	Name string `json:"name"`
+}
+
+// ModelPreferences expresses the server's priorities when the client picks a model
+type ModelPreferences struct {
+	Hints                []ModelHint `json:"hints,omitempty"`
+	CostPriority         *float64    `json:"costPriority,omitempty"`
+	SpeedPriority        *float64    `json:"speedPriority,omitempty"`
+	IntelligencePriority *float64    `json:"intelligencePriority,omitempty"`
+}
+
+// SamplingRequest holds the params of sampling/createMessage
+type SamplingRequest struct {
+	Messages         []SamplingMessage `json:"messages"`
+	ModelPreferences *ModelPreferences `json:"modelPreferences,omitempty"`
+	SystemPrompt     string            `json:"systemPrompt,omitempty"`
+	// IncludeContext is one of "none", "thisServer" or "allServers"
+	IncludeContext string         `json:"includeContext,omitempty"`
+	Temperature    *float64       `json:"temperature,omitempty"`
+	MaxTokens      int            `json:"maxTokens"`
+	StopSequences  []string       `json:"stopSequences,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+}
+
+// SamplingResult is the message generated by the client's model
+type SamplingResult struct {
+	Role       string          `json:"role"`
+	Content    SamplingContent `json:"content"`
+	Model      string          `json:"model"`
+	StopReason string          `json:"stopReason,omitempty"`
+}
+
+// TextMessage builds a text sampling message, role is "user" or "assistant"
+func TextMessage(role, text string) SamplingMessage {
+	return SamplingMessage{Role: role, Content: SamplingContent{Type: "text", Text: text}}
+}
+
+// CreateMessage asks the client's model for a completion with sampling/createMessage.
+// It is meant to be called from tool handlers with r.Context() and blocks until the client answers,
+// failing with ErrCapabilityNotSupported when the client did not declare the sampling capability.
+func CreateMessage(ctx context.Context, request SamplingRequest) (*SamplingResult, error) {
+	if len(request.Messages) == 0 {
+		return nil, errors.New("sampling request needs at least one message")
+	}
+	if request.MaxTokens <= 0 {
+		return nil, errors.New("sampling request needs a positive maxTokens")
+	}
+
+	session, err := sessionWithCapability(ctx, "sampling")
+	if err != nil {
+		return nil, err
+	}
+
+	var result SamplingResult
+	if err := session.Request(ctx, "sampling/createMessage", request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
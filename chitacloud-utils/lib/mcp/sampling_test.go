@@ -0,0 +1,141 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// callToolStreaming starts a tools/call on a streaming server and returns a reader over its live response
+func callToolStreaming(t *testing.T, server *Server, sessionID string, id int, name string) *bufio.Reader {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(SessionHeader, sessionID)
+	body, err := server.Handle(r, httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: id, Method: "tools/call", Params: MCPRequestParams{Name: name}})
+	if err != nil {
+		t.Fatalf("tools/call failed: %v", err)
+	}
+	t.Cleanup(func() { body.Close() })
+	return bufio.NewReader(body)
+}
+
+// postClientResponse answers a server-to-client request the way a client would
+func postClientResponse(t *testing.T, server *Server, sessionID string, id int, result any) int {
+	t.Helper()
+	raw, _ := json.Marshal(result)
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(SessionHeader, sessionID)
+	w := httptest.NewRecorder()
+	if _, err := server.Handle(r, w, MCPRequest{JSONRPC: "2.0", ID: id, Result: raw}); err != nil {
+		t.Fatalf("posting response failed: %v", err)
+	}
+	return w.Code
+}
+
+func TestCreateMessageRoundTrip(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetStreaming(true)
+	server.RegisterTool(ToolDescription{
+		Name: "summarize",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			result, err := CreateMessage(r.Context(), SamplingRequest{
+				Messages:  []SamplingMessage{TextMessage("user", "Summarize: the quick brown fox")},
+				MaxTokens: 50,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"summary": result.Content.Text, "model": result.Model}, nil
+		},
+	})
+
+	sessionID := initializeSession(t, server, map[string]any{"sampling": map[string]any{}})
+	reader := callToolStreaming(t, server, sessionID, 10, "summarize")
+
+	request := readStreamMessage(t, reader)
+	if request["method"] != "sampling/createMessage" {
+		t.Fatalf("expected a sampling request, got %v", request)
+	}
+	params := request["params"].(map[string]any)
+	if params["maxTokens"] != float64(50) {
+		t.Errorf("unexpected sampling params %v", params)
+	}
+
+	code := postClientResponse(t, server, sessionID, int(request["id"].(float64)), SamplingResult{
+		Role:    "assistant",
+		Content: SamplingContent{Type: "text", Text: "A fox."},
+		Model:   "test-model",
+	})
+	if code != http.StatusAccepted {
+		t.Errorf("expected 202 for a client response, got %d", code)
+	}
+
+	response := readStreamMessage(t, reader)
+	if response["id"] != float64(10) {
+		t.Fatalf("expected the tool result, got %v", response)
+	}
+	structured := response["result"].(map[string]any)["structuredContent"].(map[string]any)
+	if structured["summary"] != "A fox." || structured["model"] != "test-model" {
+		t.Errorf("unexpected tool result %v", structured)
+	}
+}
+
+func TestCreateMessageRequiresCapability(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetStreaming(true)
+
+	var samplingErr error
+	server.RegisterTool(ToolDescription{
+		Name: "summarize",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			_, samplingErr = CreateMessage(r.Context(), SamplingRequest{Messages: []SamplingMessage{TextMessage("user", "hi")}, MaxTokens: 10})
+			return nil, samplingErr
+		},
+	})
+
+	sessionID := initializeSession(t, server, nil)
+	reader := callToolStreaming(t, server, sessionID, 2, "summarize")
+	if response := readStreamMessage(t, reader); response["error"] == nil {
+		t.Fatalf("expected an error response, got %v", response)
+	}
+	if !errors.Is(samplingErr, ErrCapabilityNotSupported) {
+		t.Errorf("expected ErrCapabilityNotSupported, got %v", samplingErr)
+	}
+}
+
+func TestCreateMessageTimeout(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetStreaming(true)
+
+	var samplingErr error
+	server.RegisterTool(ToolDescription{
+		Name: "summarize",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			ctx, cancel := context.WithTimeout(r.Context(), 50*time.Millisecond)
+			defer cancel()
+			_, samplingErr = CreateMessage(ctx, SamplingRequest{Messages: []SamplingMessage{TextMessage("user", "hi")}, MaxTokens: 10})
+			return nil, samplingErr
+		},
+	})
+
+	sessionID := initializeSession(t, server, map[string]any{"sampling": map[string]any{}})
+	reader := callToolStreaming(t, server, sessionID, 3, "summarize")
+
+	request := readStreamMessage(t, reader)
+	if response := readStreamMessage(t, reader); response["error"] == nil {
+		t.Fatalf("expected an error response after the timeout, got %v", response)
+	}
+	if !errors.Is(samplingErr, ErrServerRequestTimeout) {
+		t.Errorf("expected ErrServerRequestTimeout, got %v", samplingErr)
+	}
+
+	// A late answer no longer matches a pending request
+	if code := postClientResponse(t, server, sessionID, int(request["id"].(float64)), map[string]any{}); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a late response, got %d", code)
+	}
+}
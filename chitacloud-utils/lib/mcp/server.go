@@ -2,25 +2,85 @@
 package mcp
 
 import (
+	"container/list"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/uuid"
 )
 
 // Server represents an MCP protocol server
 type Server struct {
-	Name           string
-	Version        string
-	Description    string
-	Tools          []ToolDescription
+	Name        string
+	Version     string
+	Description string
+	// DefaultHandler answers requests that carry no JSON-RPC method, unknown methods fail with ErrMethodNotFound
 	DefaultHandler func(r *http.Request, params map[string]any) (any, error)
 	Debug          bool
+	RateLimiter    *RateLimiter
+	// Logger receives server logs, defaults to a text logger on stdout at debug level when Debug is set
+	Logger *slog.Logger
+	// SensitiveArguments lists extra argument names redacted from logs
+	SensitiveArguments []string
+
+	// Metrics records request statistics when enabled, served on MetricsPath
+	Metrics     *Metrics
+	MetricsPath string
+	// OpenAPIPath serves the OpenAPI document of the tools when set, see EnableOpenAPI
+	OpenAPIPath string
+	// RESTPrefix exposes every tool as a plain JSON endpoint under the prefix when set, see EnableREST
+	RESTPrefix string
+	// Tracer creates spans for method dispatch and tool execution when set
+	Tracer *Tracer
+	// SpanFlushTimeout is how long each request waits for its spans to be exported before returning, zero
+	// leaves them to the background export of Tracer
+	SpanFlushTimeout time.Duration
+	// CORS overrides DefaultCORSPolicy, it also decides which Origin headers are accepted
+	CORS *CORSPolicy
+	// Streaming is set when the runtime streams response bodies, enabling server notifications on GET streams
+	Streaming bool
+	// KeepAlive is the interval of SSE comment heartbeats sent while a streamed tools/call runs, zero disables them
+	KeepAlive time.Duration
+	// Limits bounds request sizes, argument nesting and result sizes
+	Limits Limits
+	// StatelessSessions adopts unknown session ids instead of answering them with 404, see SetStatelessSessions
+	StatelessSessions bool
+
+	tools ToolRegistry
+
+	resourcesMu       sync.RWMutex
+	resources         map[string]Resource
+	resourceOrder     []string
+	resourceTemplates map[string]ResourceTemplate
+	templateOrder     []string
+
+	promptsMu   sync.RWMutex
+	prompts     map[string]Prompt
+	promptOrder []string
+
+	completionsMu sync.RWMutex
+	completions   map[completionKey]CompletionProvider
+
+	sessionsMu sync.Mutex
+	sessions   map[string]*Session
+	// sessionOrder lists the sessions from the most to the least recently used
+	sessionOrder *list.List
+
+	// gateways are the servers this one is mounted on, see Mount
+	gatewaysMu sync.Mutex
+	gateways   []*Server
 }
 
 // NewServer creates a new MCP server with the given parameters
@@ -29,7 +89,6 @@ func NewServer(name, version, description string) *Server {
 		Name:        name,
 		Version:     version,
 		Description: description,
-		Tools:       []ToolDescription{},
 		Debug:       false,
 	}
 }
@@ -38,9 +97,9 @@ func (s *Server) SetDebug(debug bool) {
 	s.Debug = debug
 }
 
-// RegisterTool adds a tool to the server's available tools
-func (s *Server) RegisterTool(tool ToolDescription) {
-	s.Tools = append(s.Tools, tool)
+// SetRateLimiter enables rate limiting of incoming requests, nil disables it
+func (s *Server) SetRateLimiter(limiter *RateLimiter) {
+	s.RateLimiter = limiter
 }
 
 func (s *Server) SetDefaultHandler(handler func(r *http.Request, params map[string]any) (any, error)) {
@@ -49,67 +108,340 @@ func (s *Server) SetDefaultHandler(handler func(r *http.Request, params map[stri
 
 func (s *Server) Handle(r *http.Request, w http.ResponseWriter, req MCPRequest) (io.ReadCloser, error) {
 
-	if s.Debug {
-		fmt.Println("MCP request:", req.JSONRPC, req.ID, req.Method)
-		fmt.Println("Request headers:", r.Header)
-		// DEBUG, print body
-		fmt.Println("[DEBUG] Request body:", string(req.LambdaRequest.Payload))
+	if s.isMetricsRequest(r) {
+		return s.serveMetrics(w)
+	}
+	if s.isOpenAPIRequest(r) {
+		return s.serveOpenAPI(r, w)
+	}
+
+	start := time.Now()
+	logger := s.requestLogger(r, req)
+	defer s.flushSpans(logger)
+
+	var session *Session
+	var sessionErr error
+	if req.Method != "initialize" {
+		session, sessionErr = s.sessionFor(r)
+	}
+	stream := &eventStream{}
+	r = r.WithContext(s.requestContext(r.Context(), logger, session, stream, req))
+
+	logger.Debug("MCP request", "jsonrpc", req.JSONRPC, "headers", RedactHeaders(r.Header))
 
+	if r.Method != "OPTIONS" {
+		if err := s.corsPolicy().ValidateOrigin(r); err != nil {
+			logger.Warn("Rejected request from disallowed origin", "origin", r.Header.Get("Origin"))
+			return rejectRequest(w, http.StatusForbidden, req.ID, err)
+		}
 	}
 
-	mcpInfo, err := InitHttp(r, w, req)
+	if sessionErr != nil {
+		// The Streamable HTTP transport answers unknown sessions with 404 so the client initializes again
+		logger.Warn("Rejected request for unknown session", "error", sessionErr)
+		return rejectRequest(w, http.StatusNotFound, req.ID, &JsonRPCError{Code: ErrInvalidRequest, Message: sessionErr.Error()})
+	}
+
+	if name, ok := s.restToolName(r); ok {
+		return s.serveREST(r, w, req, name, start)
+	}
+
+	if err := s.checkRequestSize(r, req); err != nil {
+		logger.Warn("Rejected oversized request", "error", err)
+		return rejectRequest(w, http.StatusRequestEntityTooLarge, req.ID, err)
+	}
+
+	mcpInfo, err := initHttp(r, w, req, s.corsPolicy())
 	if err != nil {
+		logger.Error("Failed to initialize MCP response", "error", err)
 		return nil, err
 	} else if mcpInfo.IsPreflight {
 		return nil, nil
 	}
 
+	if isClientResponse(req) {
+		return s.handleClientResponse(w, req, session)
+	}
+	if isClientNotification(req) {
+		return s.handleClientNotification(r, w, req, session)
+	}
+
+	if s.wantsStream(r, req) {
+		if session == nil {
+			return rejectRequest(w, http.StatusBadRequest, req.ID, &JsonRPCError{Code: ErrInvalidRequest, Message: SessionHeader + " header is required to open a stream"})
+		}
+		logger.Debug("Opening SSE stream")
+		return s.openStream(r, session), nil
+	}
+
+	logger.Debug("MCP request params", "arguments", s.RedactArguments(req.Params.Arguments), "meta", req.Params.Meta)
+
+	remote, _ := extractTraceContext(r, req.Params)
+	ctx, span := s.startSpan(r.Context(), "mcp "+mcpInfo.Method, SpanKindServer, remote)
+	span.SetAttribute("rpc.system", "jsonrpc")
+	span.SetAttribute("rpc.method", mcpInfo.Method)
+	span.SetAttribute("rpc.jsonrpc.request_id", req.ID)
+	if session != nil {
+		span.SetAttribute("mcp.session.id", session.ID)
+	}
+	r = r.WithContext(ctx)
+
+	if err := s.checkRateLimit(r, w, req); err != nil {
+		span.RecordError(err)
+		span.End()
+		s.observeRequest(metricsLabel(mcpInfo.Method), err, start)
+		return Response(mcpInfo, nil, err, nil, req.Params)
+	}
+
+	if mcpInfo.Method == "tools/call" && s.Streaming {
+		// The handler may talk to the client while it runs, so the response is streamed as it goes
+		live := newSSEStream(nil)
+		live.closeWhenDone(r.Context())
+		live.keepAlive(s.KeepAlive)
+		r = r.WithContext(s.requestContext(r.Context(), logger, session, live, req))
+		go func() {
+			defer s.flushSpans(logger)
+			defer live.CloseAfterFlush()
+			body, err := s.respond(r, w, req, mcpInfo, session, span, start)
+			if err != nil {
+				logger.Error("Failed to stream tool response", "error", err)
+				return
+			}
+			defer body.Close()
+			payload, err := io.ReadAll(body)
+			if err == nil {
+				err = live.SendWait(payload)
+			}
+			if err != nil {
+				logger.Error("Failed to stream tool response", "error", err)
+			}
+		}()
+		return live, nil
+	}
+
+	body, err := s.respond(r, w, req, mcpInfo, session, span, start)
+	if err != nil {
+		return nil, err
+	}
+	if events := stream.String(); events != "" {
+		body = io.NopCloser(io.MultiReader(strings.NewReader(events), body))
+	}
+	return body, nil
+}
+
+// respond dispatches the request and formats its response, ending the request span
+func (s *Server) respond(r *http.Request, w http.ResponseWriter, req MCPRequest, mcpInfo MCPInfo, session *Session, span *Span, start time.Time) (io.ReadCloser, error) {
+	defer span.End()
+
+	responseData, tool, metricsMethod, err := s.dispatch(r, w, req, mcpInfo, session)
+
+	span.RecordError(err)
+	s.observeRequest(metricsMethod, err, start)
+
+	return Response(mcpInfo, responseData, err, tool, req.Params)
+}
+
+// dispatch runs the handler of the request method, returning the metrics label used for it
+func (s *Server) dispatch(r *http.Request, w http.ResponseWriter, req MCPRequest, mcpInfo MCPInfo, session *Session) (any, *ToolDescription, string, error) {
+	logger := LoggerFromContext(r.Context())
+
 	// Prepare the response based on path
 	var responseData any
 	var tool *ToolDescription
+	var err error
+	metricsMethod := metricsLabel(mcpInfo.Method)
 
 	// Handle different MCP protocol paths
 	switch mcpInfo.Method {
 	case "initialize":
-		// Initialize request - return server capabilities
-		responseData = s.HandleInitialize()
-		if s.Debug {
-			fmt.Println("Sending initialize response")
-		}
+		// Initialize request - start a session and return server capabilities
+		session = s.newSession(req.Params)
+		w.Header().Set(SessionHeader, session.ID)
+		initialize := s.HandleInitialize()
+		initialize["protocolVersion"] = session.ProtocolVersion
+		responseData = initialize
+		logger.Debug("Sending initialize response", "session", session.ID)
+
+	case "ping":
+		// Liveness check, answered with an empty result
+		responseData = map[string]any{}
+
+	case "logging/setLevel":
+		responseData, err = handleSetLevel(session, req.Params.Level)
+
+	case "completion/complete":
+		responseData, err = s.handleComplete(r, req.Params)
+
+	case "resources/list":
+		responseData = map[string]any{"resources": s.Resources()}
+
+	case "resources/templates/list":
+		responseData = map[string]any{"resourceTemplates": s.ResourceTemplates()}
+
+	case "resources/read":
+		responseData, err = s.handleReadResource(r, req.Params.URI)
+
+	case "resources/subscribe", "resources/unsubscribe":
+		responseData, err = s.handleSubscription(session, req.Params.URI, mcpInfo.Method == "resources/subscribe")
+
+	case "prompts/list":
+		responseData = map[string]any{"prompts": s.Prompts()}
+
+	case "prompts/get":
+		responseData, err = s.handleGetPrompt(r, req.Params.Name, req.Params.Arguments)
 
 	case "tools/list":
 		// List tools request
 		responseData = s.HandleTools()
-		if s.Debug {
-			fmt.Println("Sending tools list response")
-		}
+		logger.Debug("Sending tools list response")
 
 	case "tools/call":
-		toolName := req.Params.Name
-
-		if tool = s.FindTool(toolName); tool != nil {
-			responseData, err = tool.Handler(r, req.Params.Arguments)
-			if err != nil {
-				fmt.Printf("Error calling tool %s: %s\n", toolName, err.Error())
-			}
-		} else {
-			fmt.Printf("Tool %s not found\n", toolName)
-			err = errors.New("tool not found")
-		}
+		responseData, tool, err = s.callTool(r, req.Params.Name, req.Params.Arguments)
 	default:
+		if req.Method != "" {
+			logger.Warn("Method not found")
+			err = &JsonRPCError{Code: ErrMethodNotFound, Message: "method not found: " + req.Method}
+			break
+		}
 		if s.DefaultHandler == nil {
-			fmt.Printf("[DEBUG] Default handler not set\n")
+			logger.Debug("Default handler not set")
 			responseData = map[string]any{"status": "OK"}
 		} else {
 			responseData, err = s.DefaultHandler(r, req.Params.Arguments)
 		}
 
-		if s.Debug {
-			fmt.Println("Sending default path response")
+		logger.Debug("Sending default path response")
+	}
+
+	return responseData, tool, metricsMethod, err
+}
+
+// callTool runs the handler of the named tool, recording its span and metrics
+func (s *Server) callTool(r *http.Request, name string, arguments map[string]any) (any, *ToolDescription, error) {
+	logger := LoggerFromContext(r.Context())
+	tool := s.FindTool(name)
+	if tool == nil {
+		logger.Warn("Tool not found")
+		return nil, nil, &JsonRPCError{Code: ErrInvalidParams, Message: fmt.Sprintf("%s: %s", ErrToolNotFound, name)}
+	}
+	if err := s.checkArguments(arguments); err != nil {
+		logger.Warn("Rejected tool arguments", "error", err)
+		return nil, tool, err
+	}
+
+	var cacheKey string
+	if tool.Cache != nil {
+		key, err := tool.Cache.key(r, name, arguments)
+		if err != nil {
+			logger.Warn("Failed to derive cache key", "error", err)
+		} else {
+			// Cache-Control: no-cache skips the lookup, the fresh result still refreshes the entry
+			if !bypassCache(r) {
+				if cached, ok := tool.Cache.store().Get(key); ok {
+					s.observeCache(name, true)
+					logger.Debug("Serving tool result from cache")
+					return cached, tool, nil
+				}
+			}
+			s.observeCache(name, false)
+			cacheKey = key
 		}
 	}
 
-	return Response(mcpInfo, responseData, err, tool, req.Params)
+	toolStart := time.Now()
+	toolCtx, toolSpan := s.startSpan(r.Context(), "tools/call "+name, SpanKindInternal, SpanContext{})
+	toolSpan.SetAttribute("mcp.tool.name", name)
+	directive := &cacheDirective{}
+	toolCtx = context.WithValue(toolCtx, cacheContextKey{}, directive)
+	result, err := tool.Handler(r.WithContext(toolCtx), arguments)
+	if err == nil {
+		result, err = s.limitResult(tool, result)
+	}
+	toolSpan.RecordError(err)
+	toolSpan.End()
+	if s.Metrics != nil {
+		s.Metrics.ObserveToolCall(name, err, time.Since(toolStart))
+	}
+	if err != nil {
+		logger.Error("Error calling tool", "error", err)
+	} else if cacheKey != "" && !directive.skipped() && s.isRegistered(tool) {
+		tool.Cache.store().Set(cacheKey, result, tool.Cache.TTL)
+	}
+	return result, tool, err
+}
+
+// rejectRequest answers with an HTTP error status and a JSON-RPC error body
+func rejectRequest(w http.ResponseWriter, status int, id int, err error) (io.ReadCloser, error) {
+	body, marshalErr := FormatMCPServerResponse(id, "", "", nil, nil, err)
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return io.NopCloser(strings.NewReader(string(body))), nil
+}
+
+func (s *Server) observeCache(tool string, hit bool) {
+	if s.Metrics != nil {
+		s.Metrics.ObserveCache(tool, hit)
+	}
+}
+
+// dispatchedMethods are the methods dispatch answers by name
+var dispatchedMethods = map[string]bool{
+	"initialize": true, "ping": true, "logging/setLevel": true, "completion/complete": true,
+	"resources/list": true, "resources/templates/list": true, "resources/read": true,
+	"resources/subscribe": true, "resources/unsubscribe": true, "prompts/list": true, "prompts/get": true,
+	"tools/list": true, "tools/call": true,
+}
+
+// metricsLabel groups arbitrary method names under "default" to keep metric labels bounded
+func metricsLabel(method string) string {
+	if dispatchedMethods[method] {
+		return method
+	}
+	return "default"
+}
+
+func (s *Server) observeRequest(method string, err error, start time.Time) {
+	if s.Metrics != nil {
+		s.Metrics.ObserveRequest(method, err, time.Since(start))
+	}
+}
+
+// requestContext attaches the per-request logger, session and client log stream to ctx
+func (s *Server) requestContext(ctx context.Context, logger *slog.Logger, session *Session, stream outboundStream, req MCPRequest) context.Context {
+	ctx = ContextWithLogger(ctx, logger)
+	ctx = context.WithValue(ctx, sessionContextKey{}, session)
+	ctx = contextWithStream(ctx, stream)
+	loggerName := s.Name
+	if req.Method == "tools/call" {
+		loggerName = req.Params.Name
+	}
+	return contextWithClientLogger(ctx, session, stream, loggerName)
+}
+
+// checkRateLimit consumes a token for the request, tool calls use the bucket of the called tool
+func (s *Server) checkRateLimit(r *http.Request, w http.ResponseWriter, req MCPRequest) error {
+	if s.RateLimiter == nil {
+		return nil
+	}
+
+	var tool *ToolDescription
+	if req.Method == "tools/call" {
+		tool = s.FindTool(req.Params.Name)
+	}
+
+	err := s.RateLimiter.Allow(r, tool)
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		// w is nil for calls that do not come from an HTTP request of their own, such as function calls
+		if w != nil {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+		}
+		LoggerFromContext(r.Context()).Warn("Rate limit exceeded", "retryAfter", rateLimitErr.RetryAfter)
+	}
+	return err
 }
 
 func wrapToValidToolCallResponse(entry any) (map[string]any, error) {
@@ -127,20 +459,18 @@ func wrapToValidToolCallResponse(entry any) (map[string]any, error) {
 	return wrappedEntry, nil
 }
 
-func (s *Server) FindTool(name string) *ToolDescription {
-	for _, tool := range s.Tools {
-		if tool.Name == name {
-			return &tool
-		}
+// isCallToolResult reports whether a tool already returned a CallToolResult with its content blocks
+func isCallToolResult(result any) bool {
+	if m, ok := result.(map[string]any); ok {
+		content, ok := m["content"]
+		return ok && reflect.ValueOf(content).Kind() == reflect.Slice
 	}
-	return nil
+	return false
 }
 
 // SetCORSHeaders sets standard CORS headers to allow MCP Inspector to connect
 func SetCORSHeaders(w http.ResponseWriter) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	DefaultCORSPolicy().Apply(w, "")
 }
 
 // SetSSEHeaders sets standard Server-Sent Events headers
@@ -153,11 +483,22 @@ func SetSSEHeaders(w http.ResponseWriter) {
 // HandleInitialize creates the initialize response data
 func (s *Server) HandleInitialize() map[string]any {
 	return map[string]any{
-		"protocolVersion": "2024-11-05",
+		"protocolVersion": LatestProtocolVersion,
 		"capabilities": map[string]any{
 			"tools": map[string]any{
-				"listChanged": true,
+				// Changes can only be announced on the GET stream
+				"listChanged": s.Streaming,
+			},
+			"resources": map[string]any{
+				// Updates and list changes can only be delivered on the GET stream
+				"subscribe":   s.Streaming,
+				"listChanged": s.Streaming,
 			},
+			"prompts": map[string]any{
+				"listChanged": s.Streaming,
+			},
+			"logging":     map[string]any{},
+			"completions": map[string]any{},
 		},
 		"serverInfo": map[string]any{
 			"name":        s.Name,
@@ -169,8 +510,15 @@ func (s *Server) HandleInitialize() map[string]any {
 
 // HandleTools creates the tools list response data
 func (s *Server) HandleTools() map[string]interface{} {
+	tools := s.Tools()
+	for i := range tools {
+		// MCP requires an input schema, tools without arguments accept an empty object
+		if tools[i].InputSchema == nil {
+			tools[i].InputSchema = &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeObject}}
+		}
+	}
 	return map[string]interface{}{
-		"tools": s.Tools,
+		"tools": tools,
 	}
 }
 
@@ -191,9 +539,13 @@ type MCPInfo struct {
 }
 
 func InitHttp(r *http.Request, w http.ResponseWriter, req MCPRequest) (MCPInfo, error) {
+	return initHttp(r, w, req, DefaultCORSPolicy())
+}
 
-	// Set CORS headers to allow MCP Inspector to connect
-	SetCORSHeaders(w)
+func initHttp(r *http.Request, w http.ResponseWriter, req MCPRequest, cors CORSPolicy) (MCPInfo, error) {
+
+	// Set CORS headers to allow MCP Inspector and allowed browser origins to connect
+	cors.Apply(w, r.Header.Get("Origin"))
 
 	// Handle preflight OPTIONS request
 	if r.Method == "OPTIONS" {
@@ -204,17 +556,6 @@ func InitHttp(r *http.Request, w http.ResponseWriter, req MCPRequest) (MCPInfo,
 	// Set SSE headers
 	SetSSEHeaders(w)
 
-	var err error
-
-	// Define default request ID for JSON-RPC 2.0
-	requestID := req.ID
-
-	paramsB, err := json.Marshal(req.Params)
-	if err != nil {
-		return MCPInfo{RequestID: requestID}, err
-	}
-	fmt.Printf("Params: %s\n", string(paramsB))
-
 	// Use request method if available, otherwise use path-derived method
 	method := req.Method
 	if method == "" {
@@ -270,6 +611,15 @@ func Response(mcpInfo MCPInfo, responseData any, err error, tool *ToolDescriptio
 		buffer.WriteString(fmt.Sprintf("data: %s\n\n", string(finalResponse)))
 
 	} else {
+		// Tool results are wrapped into a CallToolResult unless the tool returns its own
+		if mcpInfo.Method == "tools/call" && err == nil && (tool == nil || !tool.Raw) && !isCallToolResult(responseData) {
+			wrapped, wrapErr := wrapToValidToolCallResponse(responseData)
+			if wrapErr != nil {
+				return nil, fmt.Errorf("failed to wrap tool response: %w", wrapErr)
+			}
+			responseData = wrapped
+		}
+
 		// If it's not a slice, handle as a single response
 		responseBody, err := FormatMCPServerResponse(mcpInfo.RequestID, mcpInfo.Method, mcpInfo.StreamID, responseData, nil, err)
 		if err != nil {
@@ -0,0 +1,168 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultServerRequestTimeout bounds how long a server-to-client request waits for its response
+// when the caller's context has no deadline
+const DefaultServerRequestTimeout = 60 * time.Second
+
+var (
+	// ErrCapabilityNotSupported is returned when the client did not declare the capability a request needs
+	ErrCapabilityNotSupported = errors.New("client does not support this capability")
+	// ErrServerRequestTimeout is returned when the client does not answer a server request in time
+	ErrServerRequestTimeout = errors.New("timed out waiting for the client response")
+)
+
+// clientReply is the response a client POSTs for a server-to-client request
+type clientReply struct {
+	result json.RawMessage
+	err    *JsonRPCError
+}
+
+// isClientResponse reports whether req is the client's answer to a server-to-client request
+func isClientResponse(req MCPRequest) bool {
+	return req.Method == "" && (req.Result != nil || req.Error != nil)
+}
+
+// handleClientResponse delivers a client response to the request waiting for it
+func (s *Server) handleClientResponse(w http.ResponseWriter, req MCPRequest, session *Session) (io.ReadCloser, error) {
+	if session == nil || !session.resolve(req.ID, clientReply{result: req.Result, err: req.Error}) {
+		return rejectRequest(w, http.StatusBadRequest, req.ID, &JsonRPCError{Code: ErrInvalidRequest, Message: fmt.Sprintf("no pending request with id %d", req.ID)})
+	}
+	w.WriteHeader(http.StatusAccepted)
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// isClientNotification reports whether req is a notification sent by the client, which expects no response
+func isClientNotification(req MCPRequest) bool {
+	return strings.HasPrefix(req.Method, "notifications/")
+}
+
+// handleClientNotification reacts to a client notification and acknowledges it without a body
+func (s *Server) handleClientNotification(r *http.Request, w http.ResponseWriter, req MCPRequest, session *Session) (io.ReadCloser, error) {
+	LoggerFromContext(r.Context()).Debug("Received client notification")
+
+	switch req.Method {
+	case "notifications/roots/list_changed":
+		if session != nil {
+			s.rootsChanged(session)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// resolve hands reply to the pending request with the given id, reporting whether one was waiting
+func (s *Session) resolve(id int, reply clientReply) bool {
+	s.mu.Lock()
+	pending, ok := s.pending[id]
+	delete(s.pending, id)
+	s.mu.Unlock()
+
+	if ok {
+		pending <- reply
+	}
+	return ok
+}
+
+// anyStream returns one of the GET streams kept open by the client
+func (s *Session) anyStream() outboundStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for stream := range s.streams {
+		return stream
+	}
+	return nil
+}
+
+// Request sends a server-to-client request and decodes the client's result into result.
+// It uses the live response stream of the current request when there is one, otherwise a GET stream
+// of the session, and waits until ctx is done or DefaultServerRequestTimeout elapses.
+func (s *Session) Request(ctx context.Context, method string, params any, result any) error {
+	var stream outboundStream
+	if live, ok := streamFromContext(ctx).(*sseStream); ok {
+		stream = live
+	} else if stream = s.anyStream(); stream == nil {
+		return fmt.Errorf("%s: %w", method, ErrNoOpenStream)
+	}
+
+	replies := make(chan clientReply, 1)
+	s.mu.Lock()
+	s.lastRequestID++
+	id := s.lastRequestID
+	if s.pending == nil {
+		s.pending = map[int]chan clientReply{}
+	}
+	s.pending[id] = replies
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	request := map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+	}
+	if params != nil {
+		request["params"] = params
+	}
+	message, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+	if err := stream.Send([]byte(fmt.Sprintf("data: %s\n\n", string(message)))); err != nil {
+		return fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultServerRequestTimeout)
+		defer cancel()
+	}
+
+	select {
+	case reply := <-replies:
+		if reply.err != nil {
+			return reply.err
+		}
+		if result == nil || len(reply.result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(reply.result, result); err != nil {
+			return fmt.Errorf("invalid %s result: %w", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s: %w", method, ErrServerRequestTimeout)
+		}
+		return ctx.Err()
+	}
+}
+
+// sessionWithCapability returns the session of ctx when its client declared capability
+func sessionWithCapability(ctx context.Context, capability string) (*Session, error) {
+	session := SessionFromContext(ctx)
+	if session == nil {
+		return nil, errors.New("server requests need an initialized session")
+	}
+	if !session.HasCapability(capability) {
+		return nil, fmt.Errorf("%s: %w", capability, ErrCapabilityNotSupported)
+	}
+	return session, nil
+}
@@ -0,0 +1,357 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionHeader is the HTTP header carrying the MCP session id
+const SessionHeader = "Mcp-Session-Id"
+
+// ProtocolVersionHeader is the HTTP header carrying the negotiated protocol version
+const ProtocolVersionHeader = "Mcp-Protocol-Version"
+
+// LatestProtocolVersion is the most recent MCP revision implemented by the server
+const LatestProtocolVersion = "2025-06-18"
+
+// SupportedProtocolVersions lists the MCP revisions the server accepts during initialize
+var SupportedProtocolVersions = []string{LatestProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// negotiateProtocolVersion keeps the version requested by the client when supported, otherwise the latest one
+func negotiateProtocolVersion(requested string) string {
+	for _, version := range SupportedProtocolVersions {
+		if version == requested {
+			return version
+		}
+	}
+	return LatestProtocolVersion
+}
+
+// ErrSessionNotFound is returned for requests naming a session the server does not know
+var ErrSessionNotFound = errors.New("session not found")
+
+// sessionIdleTimeout is how long a session is kept without requests
+const sessionIdleTimeout = time.Hour
+
+// maxSessions caps the sessions kept in memory, the least recently used one is dropped beyond it
+const maxSessions = 10000
+
+// maxSessionIDLength bounds the session ids adopted by servers with StatelessSessions
+const maxSessionIDLength = 128
+
+// Session holds the state negotiated with a client during initialize
+type Session struct {
+	ID                 string
+	ProtocolVersion    string
+	ClientInfo         map[string]any
+	ClientCapabilities map[string]any
+	CreatedAt          time.Time
+
+	mu       sync.Mutex
+	logLevel string
+	lastSeen time.Time
+	streams  map[*sseStream]struct{}
+	// element is the position of the session in Server.sessionOrder, guarded by Server.sessionsMu
+	element *list.Element
+
+	lastRequestID int
+	pending       map[int]chan clientReply
+
+	roots       []Root
+	rootsLoaded bool
+	// rootsGeneration counts roots/list_changed notifications, refreshes started before the last one are stale
+	rootsGeneration uint64
+
+	subscriptions map[string]struct{}
+}
+
+// LogLevel returns the minimum level requested through logging/setLevel, empty when not set
+func (s *Session) LogLevel() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logLevel
+}
+
+// SetLogLevel sets the minimum level of log messages sent to the client
+func (s *Session) SetLogLevel(level string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logLevel = level
+}
+
+// HasCapability reports whether the client declared the given capability during initialize
+func (s *Session) HasCapability(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.ClientCapabilities[name]
+	return ok
+}
+
+// ErrNoOpenStream is returned when notifying a client that keeps no SSE stream open
+var ErrNoOpenStream = errors.New("client has no open stream")
+
+// Notify sends a notification to every SSE stream the client keeps open
+func (s *Session) Notify(method string, params any) error {
+	s.mu.Lock()
+	streams := make([]*sseStream, 0, len(s.streams))
+	for stream := range s.streams {
+		streams = append(streams, stream)
+	}
+	s.mu.Unlock()
+
+	if len(streams) == 0 {
+		return ErrNoOpenStream
+	}
+	var errs []error
+	for _, stream := range streams {
+		if err := stream.Notify(method, params); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HasOpenStream reports whether the client keeps an SSE stream open
+func (s *Session) HasOpenStream() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.streams) > 0
+}
+
+func (s *Session) attachStream(stream *sseStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streams == nil {
+		s.streams = map[*sseStream]struct{}{}
+	}
+	s.streams[stream] = struct{}{}
+}
+
+func (s *Session) detachStream(stream *sseStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, stream)
+}
+
+func (s *Session) closeStreams() {
+	s.mu.Lock()
+	streams := s.streams
+	s.streams = nil
+	s.mu.Unlock()
+	for stream := range streams {
+		stream.Close()
+	}
+}
+
+func (s *Session) touch(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSeen = now
+}
+
+func (s *Session) idleSince(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Sub(s.lastSeen)
+}
+
+type sessionContextKey struct{}
+
+// SessionFromContext returns the session of the request being handled, nil when the client sent none
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return session
+}
+
+// SetStatelessSessions makes the server adopt session ids it does not know instead of answering them with 404,
+// for deployments whose instances share no state such as lambdas. Adopted sessions know no client capabilities.
+func (s *Server) SetStatelessSessions(stateless bool) {
+	s.StatelessSessions = stateless
+}
+
+// newSession creates and stores a session for a client sending initialize
+func (s *Server) newSession(params MCPRequestParams) *Session {
+	now := time.Now()
+	session := &Session{
+		ID:                 uuid.New().String(),
+		ProtocolVersion:    negotiateProtocolVersion(params.ProtocolVersion),
+		ClientInfo:         params.ClientInfo,
+		ClientCapabilities: params.Capabilities,
+		CreatedAt:          now,
+		lastSeen:           now,
+	}
+	return s.storeSession(session, now)
+}
+
+// adoptSession creates a session under an id issued by another instance or before a cold start
+func (s *Server) adoptSession(id, protocolVersion string, now time.Time) *Session {
+	session := &Session{
+		ID:              id,
+		ProtocolVersion: negotiateProtocolVersion(protocolVersion),
+		CreatedAt:       now,
+		lastSeen:        now,
+	}
+	return s.storeSession(session, now)
+}
+
+// storeSession adds session as the most recently used one, dropping idle sessions and those beyond maxSessions.
+// It returns the session already stored under the same id when a concurrent request adopted it first.
+func (s *Server) storeSession(session *Session, now time.Time) *Session {
+	s.sessionsMu.Lock()
+	if s.sessions == nil {
+		s.sessions = map[string]*Session{}
+		s.sessionOrder = list.New()
+	}
+	if existing := s.sessions[session.ID]; existing != nil {
+		s.sessionOrder.MoveToFront(existing.element)
+		s.sessionsMu.Unlock()
+		return existing
+	}
+	s.sessions[session.ID] = session
+	session.element = s.sessionOrder.PushFront(session)
+
+	// The least recently used sessions are at the back, so only evicted ones are visited
+	var evicted []*Session
+	for back := s.sessionOrder.Back(); back != nil; back = s.sessionOrder.Back() {
+		oldest := back.Value.(*Session)
+		if len(s.sessions) <= maxSessions && oldest.idleSince(now) <= sessionIdleTimeout {
+			break
+		}
+		s.removeSessionLocked(oldest)
+		evicted = append(evicted, oldest)
+	}
+	s.sessionsMu.Unlock()
+
+	for _, stale := range evicted {
+		stale.closeStreams()
+	}
+	return session
+}
+
+func (s *Server) removeSessionLocked(session *Session) {
+	delete(s.sessions, session.ID)
+	s.sessionOrder.Remove(session.element)
+}
+
+// sessionFor returns the session named by the request header, nil when the header is absent. Unknown or expired
+// ids fail with ErrSessionNotFound so clients initialize a new session, unless StatelessSessions is set.
+func (s *Server) sessionFor(r *http.Request) (*Session, error) {
+	id := r.Header.Get(SessionHeader)
+	if id == "" {
+		return nil, nil
+	}
+
+	now := time.Now()
+	s.sessionsMu.Lock()
+	session, ok := s.sessions[id]
+	if ok && session.idleSince(now) > sessionIdleTimeout {
+		s.removeSessionLocked(session)
+		ok = false
+	}
+	if ok {
+		s.sessionOrder.MoveToFront(session.element)
+	}
+	s.sessionsMu.Unlock()
+	if ok {
+		session.touch(now)
+		return session, nil
+	}
+	if s.StatelessSessions && isValidSessionID(id) {
+		return s.adoptSession(id, r.Header.Get(ProtocolVersionHeader), now), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+}
+
+// isValidSessionID accepts the visible ASCII ids allowed by the Streamable HTTP transport
+func isValidSessionID(id string) bool {
+	if len(id) > maxSessionIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Session returns the session with the given id, or nil
+func (s *Server) Session(id string) *Session {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	return s.sessions[id]
+}
+
+// CloseSession forgets the session with the given id and closes its streams
+func (s *Server) CloseSession(id string) {
+	s.sessionsMu.Lock()
+	session := s.sessions[id]
+	if session != nil {
+		s.removeSessionLocked(session)
+	}
+	s.sessionsMu.Unlock()
+
+	if session != nil {
+		session.closeStreams()
+	}
+}
+
+// Sessions returns a snapshot of the active sessions
+func (s *Server) Sessions() []*Session {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	sessions := make([]*Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// NotifyAll sends a notification to every client keeping an SSE stream open
+func (s *Server) NotifyAll(method string, params any) {
+	for _, session := range s.Sessions() {
+		if !session.HasOpenStream() {
+			continue
+		}
+		if err := session.Notify(method, params); err != nil {
+			s.logger().Warn("Failed to notify session", "session", session.ID, "notification", method, "error", err)
+		}
+	}
+}
+
+// SetStreaming declares that the lambda streams its response body, letting clients
+// keep a GET SSE stream open to receive server notifications
+func (s *Server) SetStreaming(streaming bool) {
+	s.Streaming = streaming
+}
+
+// SetKeepAlive sets the interval of heartbeats sent on streamed tools/call responses, zero disables them
+func (s *Server) SetKeepAlive(interval time.Duration) {
+	s.KeepAlive = interval
+}
+
+// wantsStream reports whether r opens the standalone SSE stream of the Streamable HTTP transport
+func (s *Server) wantsStream(r *http.Request, req MCPRequest) bool {
+	return s.Streaming && r.Method == http.MethodGet && req.Method == "" && strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// openStream returns a body kept open to deliver notifications to session
+func (s *Server) openStream(r *http.Request, session *Session) io.ReadCloser {
+	var stream *sseStream
+	stream = newSSEStream(func() {
+		session.detachStream(stream)
+	})
+	session.attachStream(stream)
+	stream.closeWhenDone(r.Context())
+	return stream
+}
@@ -0,0 +1,229 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outboundStream carries server messages to the client within the response of a request
+type outboundStream interface {
+	Send(event []byte) error
+	Notify(method string, params any) error
+}
+
+// eventStream collects the SSE events sent to the client while a request is handled,
+// they are written to the response body ahead of the final result
+type eventStream struct {
+	mu     sync.Mutex
+	buffer strings.Builder
+}
+
+type streamContextKey struct{}
+
+func contextWithStream(ctx context.Context, stream outboundStream) context.Context {
+	return context.WithValue(ctx, streamContextKey{}, stream)
+}
+
+func streamFromContext(ctx context.Context) outboundStream {
+	stream, _ := ctx.Value(streamContextKey{}).(outboundStream)
+	return stream
+}
+
+// Send queues a raw SSE event
+func (s *eventStream) Send(event []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buffer.Write(event)
+	return nil
+}
+
+// Notify queues a JSON-RPC notification on the stream
+func (s *eventStream) Notify(method string, params any) error {
+	event, err := notificationEvent(method, params)
+	if err != nil {
+		return err
+	}
+	return s.Send(event)
+}
+
+// String returns the events queued so far
+func (s *eventStream) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buffer.String()
+}
+
+// streamBufferSize is the number of events queued for a slow client before new ones are dropped
+const streamBufferSize = 64
+
+// sseStream is a long-lived SSE response body fed with events from other goroutines
+type sseStream struct {
+	reader *io.PipeReader
+	writer *io.PipeWriter
+	events chan []byte
+	done   chan struct{}
+
+	closeOnce sync.Once
+	onClose   func()
+}
+
+func newSSEStream(onClose func()) *sseStream {
+	reader, writer := io.Pipe()
+	stream := &sseStream{
+		reader:  reader,
+		writer:  writer,
+		events:  make(chan []byte, streamBufferSize),
+		done:    make(chan struct{}),
+		onClose: onClose,
+	}
+	go stream.pump()
+	return stream
+}
+
+// pump copies queued events to the response body until the stream is closed
+func (s *sseStream) pump() {
+	for {
+		select {
+		case event := <-s.events:
+			// A nil event marks the end of the stream once everything before it is written
+			if event == nil {
+				s.finish()
+				return
+			}
+			if _, err := s.writer.Write(event); err != nil {
+				s.Close()
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *sseStream) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
+
+// Close ends the stream, it is called by the runtime when the client goes away
+func (s *sseStream) Close() error {
+	s.finish()
+	s.reader.Close()
+	return nil
+}
+
+// finish stops the stream, the reader still gets what was written before io.EOF
+func (s *sseStream) finish() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.writer.Close()
+		if s.onClose != nil {
+			s.onClose()
+		}
+	})
+}
+
+// closeWhenDone closes the stream once ctx is cancelled
+func (s *sseStream) closeWhenDone(ctx context.Context) {
+	done := ctx.Done()
+	if done == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-done:
+			s.Close()
+		case <-s.done:
+		}
+	}()
+}
+
+// keepAliveEvent is an SSE comment, ignored by clients but keeping idle connections open
+var keepAliveEvent = []byte(": keepalive\n\n")
+
+// keepAlive sends a heartbeat every interval until the stream is closed, zero disables it
+func (s *sseStream) keepAlive(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// A full buffer means events are flowing anyway
+				_ = s.Send(keepAliveEvent)
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Send queues a raw SSE event, failing when the stream is closed or the client is not keeping up
+func (s *sseStream) Send(event []byte) error {
+	select {
+	case <-s.done:
+		return errStreamClosed
+	default:
+	}
+	select {
+	case s.events <- event:
+		return nil
+	case <-s.done:
+		return errStreamClosed
+	default:
+		return errors.New("stream buffer full, event dropped")
+	}
+}
+
+// SendWait queues a raw SSE event, waiting for room in the buffer until the stream is closed
+func (s *sseStream) SendWait(event []byte) error {
+	select {
+	case s.events <- event:
+		return nil
+	case <-s.done:
+		return errStreamClosed
+	}
+}
+
+// CloseAfterFlush closes the stream once the events queued so far have been written
+func (s *sseStream) CloseAfterFlush() {
+	if err := s.SendWait(nil); err != nil {
+		s.Close()
+	}
+}
+
+// Notify sends a JSON-RPC notification on the stream
+func (s *sseStream) Notify(method string, params any) error {
+	event, err := notificationEvent(method, params)
+	if err != nil {
+		return err
+	}
+	return s.Send(event)
+}
+
+var errStreamClosed = errors.New("stream closed")
+
+// notificationEvent formats a JSON-RPC notification as an SSE event
+func notificationEvent(method string, params any) ([]byte, error) {
+	notification := map[string]any{
+		"jsonrpc": "2.0",
+		"method":  method,
+	}
+	if params != nil {
+		notification["params"] = params
+	}
+	message, err := json.Marshal(notification)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s notification: %w", method, err)
+	}
+	return []byte(fmt.Sprintf("data: %s\n\n", string(message))), nil
+}
@@ -0,0 +1,217 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// initializeSession runs initialize against server and returns the new session id
+func initializeSession(t *testing.T, server *Server, capabilities map[string]any) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	body, err := server.Handle(httptest.NewRequest("POST", "/", nil), w, MCPRequest{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: MCPRequestParams{ProtocolVersion: "2025-06-18", Capabilities: capabilities}})
+	if err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+	body.Close()
+	return w.Header().Get(SessionHeader)
+}
+
+// openTestStream opens the GET SSE stream of a session
+func openTestStream(t *testing.T, server *Server, sessionID string) io.ReadCloser {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "text/event-stream")
+	r.Header.Set(SessionHeader, sessionID)
+	body, err := server.Handle(r, httptest.NewRecorder(), MCPRequest{})
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	return body
+}
+
+// readStreamMessage reads the next data event of an SSE stream, failing after a timeout
+func readStreamMessage(t *testing.T, reader *bufio.Reader) map[string]any {
+	t.Helper()
+	lines := make(chan string, 1)
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			if strings.HasPrefix(line, "data: ") {
+				lines <- strings.TrimPrefix(strings.TrimSpace(line), "data: ")
+				return
+			}
+		}
+	}()
+
+	select {
+	case line, ok := <-lines:
+		if !ok {
+			t.Fatal("stream closed before an event was received")
+		}
+		var message map[string]any
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		return message
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return nil
+}
+
+func TestToolsListChangedNotification(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetStreaming(true)
+
+	sessionID := initializeSession(t, server, nil)
+	stream := openTestStream(t, server, sessionID)
+	defer stream.Close()
+	reader := bufio.NewReader(stream)
+
+	if err := server.RegisterTool(namedTool("late", "ok")); err != nil {
+		t.Fatal(err)
+	}
+	if message := readStreamMessage(t, reader); message["method"] != "notifications/tools/list_changed" {
+		t.Errorf("expected list_changed after register, got %v", message)
+	}
+
+	if err := server.UnregisterTool("late"); err != nil {
+		t.Fatal(err)
+	}
+	if message := readStreamMessage(t, reader); message["method"] != "notifications/tools/list_changed" {
+		t.Errorf("expected list_changed after unregister, got %v", message)
+	}
+
+	stream.Close()
+	if server.Session(sessionID).HasOpenStream() {
+		t.Error("closed stream should be detached from the session")
+	}
+}
+
+func TestListChangedAdvertisedOnlyWhenStreaming(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	capabilities := server.HandleInitialize()["capabilities"].(map[string]any)
+	if capabilities["tools"].(map[string]any)["listChanged"] != false {
+		t.Error("listChanged should not be advertised without streaming")
+	}
+
+	server.SetStreaming(true)
+	capabilities = server.HandleInitialize()["capabilities"].(map[string]any)
+	if capabilities["tools"].(map[string]any)["listChanged"] != true {
+		t.Error("listChanged should be advertised with streaming")
+	}
+}
+
+func TestKeepAliveOnStreamedToolCall(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetStreaming(true)
+	server.SetKeepAlive(10 * time.Millisecond)
+
+	release := make(chan struct{})
+	server.RegisterTool(ToolDescription{
+		Name: "slow",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			<-release
+			return map[string]any{"done": true}, nil
+		},
+	})
+
+	sessionID := initializeSession(t, server, nil)
+	reader := callToolStreaming(t, server, sessionID, 4, "slow")
+
+	line, err := reader.ReadString('\n')
+	if err != nil || line != ": keepalive\n" {
+		t.Fatalf("expected a heartbeat comment, got %q (%v)", line, err)
+	}
+	close(release)
+
+	if response := readStreamMessage(t, reader); response["id"] != float64(4) {
+		t.Errorf("expected the tool result after heartbeats, got %v", response)
+	}
+}
+
+func TestUnknownSessionIsNotFound(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(SessionHeader, "forged")
+	w := httptest.NewRecorder()
+	body, err := server.Handle(r, w, MCPRequest{JSONRPC: "2.0", ID: 1, Method: "ping"})
+	if err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+	body.Close()
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown session, got %d", w.Code)
+	}
+	if len(server.Sessions()) != 0 {
+		t.Errorf("unknown session ids must not be stored, got %d sessions", len(server.Sessions()))
+	}
+
+	sessionID := initializeSession(t, server, nil)
+	r = httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(SessionHeader, sessionID)
+	w = httptest.NewRecorder()
+	if _, err := server.Handle(r, w, MCPRequest{JSONRPC: "2.0", ID: 2, Method: "ping"}); err != nil || w.Code != http.StatusOK {
+		t.Errorf("expected the issued session to be accepted, got %d %v", w.Code, err)
+	}
+}
+
+func TestStatelessSessionsAdoptUnknownIDs(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	server.SetStatelessSessions(true)
+
+	ping := func(id string) int {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set(SessionHeader, id)
+		r.Header.Set(ProtocolVersionHeader, "2025-03-26")
+		w := httptest.NewRecorder()
+		body, err := server.Handle(r, w, MCPRequest{JSONRPC: "2.0", ID: 1, Method: "ping"})
+		if err != nil {
+			t.Fatalf("Handle returned error: %v", err)
+		}
+		body.Close()
+		return w.Code
+	}
+
+	if code := ping("issued-by-another-instance"); code != http.StatusOK {
+		t.Fatalf("expected an unknown session to be adopted, got %d", code)
+	}
+	session := server.Session("issued-by-another-instance")
+	if session == nil || session.ProtocolVersion != "2025-03-26" {
+		t.Fatalf("expected the adopted session with the negotiated version, got %+v", session)
+	}
+	if ping("issued-by-another-instance"); server.Session("issued-by-another-instance") != session || len(server.Sessions()) != 1 {
+		t.Error("expected later requests to reuse the adopted session")
+	}
+	if code := ping("not visible\x01"); code != http.StatusNotFound {
+		t.Errorf("expected invalid session ids to be rejected, got %d", code)
+	}
+}
+
+func TestSessionsAreBounded(t *testing.T) {
+	server := NewServer("test", "1.0", "test")
+	idle := server.newSession(MCPRequestParams{})
+	idle.touch(time.Now().Add(-2 * sessionIdleTimeout))
+	first := server.newSession(MCPRequestParams{})
+	if server.Session(idle.ID) != nil {
+		t.Error("expected the idle session to be dropped")
+	}
+
+	for range maxSessions {
+		server.newSession(MCPRequestParams{})
+	}
+	if len(server.Sessions()) != maxSessions || server.Session(first.ID) != nil {
+		t.Errorf("expected the least recently used session to be dropped beyond %d, got %d sessions", maxSessions, len(server.Sessions()))
+	}
+}
@@ -0,0 +1,463 @@
+// Package mcp provides utilities for creating Model Context Protocol (MCP) servers
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// W3C trace context headers
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// TraceFlagSampled is the traceparent flag asking for the trace to be recorded
+const TraceFlagSampled byte = 0x01
+
+// Defaults of the background export of a Tracer
+const (
+	DefaultSpanBatchSize     = 64
+	DefaultSpanQueueSize     = 2048
+	DefaultSpanFlushInterval = time.Second
+)
+
+// ErrSpanQueueFull is reported through Tracer.OnExportError for spans dropped because the exporter lags behind
+var ErrSpanQueueFull = errors.New("span queue full, span dropped")
+
+// Span kinds as defined by OpenTelemetry
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+	SpanKindClient   = 3
+)
+
+// Span status codes as defined by OpenTelemetry
+const (
+	SpanStatusUnset = 0
+	SpanStatusOK    = 1
+	SpanStatusError = 2
+)
+
+// SpanContext identifies a span within a trace, as carried by the traceparent header
+type SpanContext struct {
+	TraceID    string
+	SpanID     string
+	Flags      byte
+	TraceState string
+}
+
+// IsSampled reports whether the sampled flag is set, spans of unsampled traces are not exported
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&TraceFlagSampled != 0
+}
+
+// IsValid reports whether the span context has non-zero trace and span ids
+func (sc SpanContext) IsValid() bool {
+	return isHexID(sc.TraceID, 32) && isHexID(sc.SpanID, 16)
+}
+
+// Traceparent formats the span context as a W3C traceparent header value
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// ParseTraceparent parses a W3C traceparent header value
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, fmt.Errorf("invalid traceparent: %q", value)
+	}
+	// Version 00 has exactly four fields, later versions may append more
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent: %q", value)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent flags: %q", value)
+	}
+	sc := SpanContext{TraceID: parts[1], SpanID: parts[2], Flags: flags[0]}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent ids: %q", value)
+	}
+	return sc, nil
+}
+
+func isHexID(id string, length int) bool {
+	if len(id) != length || strings.Trim(id, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil && strings.ToLower(id) == id
+}
+
+func randomHexID(bytes int) string {
+	b := make([]byte, bytes)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Span is a timed operation within a trace
+type Span struct {
+	Name          string
+	Kind          int
+	SpanContext   SpanContext
+	ParentSpanID  string
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]any
+	StatusCode    int
+	StatusMessage string
+
+	tracer *Tracer
+	mu     sync.Mutex
+	ended  bool
+}
+
+// SetAttribute records a key/value pair on the span
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Attributes[key] = value
+}
+
+// RecordError marks the span as failed with err
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.StatusCode = SpanStatusError
+	s.StatusMessage = err.Error()
+}
+
+// End finishes the span and hands it to the exporter unless its trace is not sampled, later calls are ignored
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+
+	if s.SpanContext.IsSampled() {
+		s.tracer.export(s)
+	}
+}
+
+// SpanExporter sends finished spans to a tracing backend
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, spans []*Span) error
+}
+
+// Tracer creates spans and exports them in batches from a background goroutine, so requests never wait for
+// the exporter. Call Flush before the process is frozen, e.g. at the end of a lambda invocation, and Shutdown
+// before it exits.
+type Tracer struct {
+	Exporter SpanExporter
+	// OnExportError is called when the exporter fails or spans are dropped, errors are dropped when nil
+	OnExportError func(err error)
+	// BatchSize is the largest number of spans per export, DefaultSpanBatchSize when zero
+	BatchSize int
+	// QueueSize is the number of finished spans waiting for export before new ones are dropped,
+	// DefaultSpanQueueSize when zero
+	QueueSize int
+	// FlushInterval is how long a span may wait for its batch to fill, DefaultSpanFlushInterval when zero
+	FlushInterval time.Duration
+
+	once     sync.Once
+	queue    chan *Span
+	flushes  chan chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+	shutdown atomic.Bool
+}
+
+// NewTracer creates a tracer exporting spans through exporter
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{Exporter: exporter}
+}
+
+// SetTracer enables tracing of requests and tool calls, nil disables it
+func (s *Server) SetTracer(tracer *Tracer) {
+	s.Tracer = tracer
+}
+
+// SetSpanFlushTimeout makes every request wait up to timeout for its spans to be exported, for processes
+// frozen between requests such as lambdas
+func (s *Server) SetSpanFlushTimeout(timeout time.Duration) {
+	s.SpanFlushTimeout = timeout
+}
+
+// flushSpans exports the spans of the request when SpanFlushTimeout is set
+func (s *Server) flushSpans(logger *slog.Logger) {
+	if s.Tracer == nil || s.SpanFlushTimeout <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.SpanFlushTimeout)
+	defer cancel()
+	if err := s.Tracer.Flush(ctx); err != nil {
+		logger.Warn("Failed to flush spans", "error", err)
+	}
+}
+
+// Start creates a span as child of the span in ctx, or of remote when ctx has none
+func (t *Tracer) Start(ctx context.Context, name string, kind int, remote SpanContext) (context.Context, *Span) {
+	parent := remote
+	if current := SpanFromContext(ctx); current != nil {
+		parent = current.SpanContext
+	}
+
+	sc := SpanContext{SpanID: randomHexID(8), Flags: TraceFlagSampled}
+	var parentSpanID string
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Flags = parent.Flags
+		sc.TraceState = parent.TraceState
+		parentSpanID = parent.SpanID
+	} else {
+		sc.TraceID = randomHexID(16)
+	}
+
+	span := &Span{
+		Name:         name,
+		Kind:         kind,
+		SpanContext:  sc,
+		ParentSpanID: parentSpanID,
+		StartTime:    time.Now(),
+		Attributes:   map[string]any{},
+		tracer:       t,
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// startSpan starts a span when tracing is enabled, the returned span is nil otherwise
+func (s *Server) startSpan(ctx context.Context, name string, kind int, remote SpanContext) (context.Context, *Span) {
+	if s.Tracer == nil {
+		return ctx, nil
+	}
+	return s.Tracer.Start(ctx, name, kind, remote)
+}
+
+// export queues a finished span for the background exporter, dropping it when the queue is full or the
+// tracer is shut down
+func (t *Tracer) export(span *Span) {
+	if t == nil || t.Exporter == nil || t.shutdown.Load() {
+		return
+	}
+	t.start()
+	select {
+	case t.queue <- span:
+	default:
+		t.exportFailed(ErrSpanQueueFull)
+	}
+}
+
+// Flush exports the spans finished so far, waiting until the exporter is done with them or ctx ends
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil || t.Exporter == nil {
+		return nil
+	}
+	t.start()
+	done := make(chan struct{})
+	select {
+	case t.flushes <- done:
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the remaining spans and stops the background goroutine, closing the exporter when it is an
+// io.Closer. Spans ending afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil || t.Exporter == nil {
+		return nil
+	}
+	t.start()
+	if !t.shutdown.CompareAndSwap(false, true) {
+		return nil
+	}
+	close(t.stop)
+	select {
+	case <-t.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if closer, ok := t.Exporter.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (t *Tracer) start() {
+	t.once.Do(func() {
+		queueSize := t.QueueSize
+		if queueSize <= 0 {
+			queueSize = DefaultSpanQueueSize
+		}
+		t.queue = make(chan *Span, queueSize)
+		t.flushes = make(chan chan struct{})
+		t.stop = make(chan struct{})
+		t.stopped = make(chan struct{})
+		go t.run()
+	})
+}
+
+// run batches the queued spans, exporting a batch when it is full, when FlushInterval elapses or on Flush,
+// until Shutdown
+func (t *Tracer) run() {
+	defer close(t.stopped)
+	batchSize := t.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultSpanBatchSize
+	}
+	interval := t.FlushInterval
+	if interval <= 0 {
+		interval = DefaultSpanFlushInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	exportBatch := func() {
+		if len(batch) > 0 {
+			if err := t.Exporter.ExportSpans(context.Background(), batch); err != nil {
+				t.exportFailed(err)
+			}
+			batch = nil
+		}
+	}
+	add := func(span *Span) {
+		batch = append(batch, span)
+		if len(batch) >= batchSize {
+			exportBatch()
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-t.queue:
+				add(span)
+			default:
+				exportBatch()
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case span := <-t.queue:
+			add(span)
+		case <-ticker.C:
+			exportBatch()
+		case done := <-t.flushes:
+			drain()
+			close(done)
+		case <-t.stop:
+			drain()
+			return
+		}
+	}
+}
+
+func (t *Tracer) exportFailed(err error) {
+	if t.OnExportError != nil {
+		t.OnExportError(err)
+	}
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the active span, nil when tracing is disabled
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// InjectTraceContext sets the traceparent and tracestate headers of the active span on header
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	header.Set(TraceparentHeader, span.SpanContext.Traceparent())
+	if span.SpanContext.TraceState != "" {
+		header.Set(TracestateHeader, span.SpanContext.TraceState)
+	}
+}
+
+// TracingTransport is an http.RoundTripper propagating the trace context of outgoing requests
+type TracingTransport struct {
+	Base http.RoundTripper
+}
+
+// NewTracingTransport wraps base, http.DefaultTransport when nil
+func NewTracingTransport(base http.RoundTripper) *TracingTransport {
+	return &TracingTransport{Base: base}
+}
+
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if SpanFromContext(req.Context()) == nil {
+		return base.RoundTrip(req)
+	}
+	// RoundTrippers must not modify the request they are given
+	req = req.Clone(req.Context())
+	InjectTraceContext(req.Context(), req.Header)
+	return base.RoundTrip(req)
+}
+
+// extractTraceContext reads the remote parent from the trace headers, falling back to params._meta
+func extractTraceContext(r *http.Request, params MCPRequestParams) (SpanContext, error) {
+	traceparent := r.Header.Get(TraceparentHeader)
+	tracestate := r.Header.Get(TracestateHeader)
+	if traceparent == "" {
+		traceparent, _ = params.Meta[TraceparentHeader].(string)
+		tracestate, _ = params.Meta[TracestateHeader].(string)
+	}
+	if traceparent == "" {
+		return SpanContext{}, errors.New("no trace context")
+	}
+
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return SpanContext{}, err
+	}
+	sc.TraceState = tracestate
+	return sc, nil
+}
@@ -0,0 +1,285 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingExporter struct {
+	mu      sync.Mutex
+	spans   []*Span
+	batches int
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	e.batches++
+	return nil
+}
+
+// flushSpans waits for the spans finished so far to reach the exporter
+func flushSpans(t *testing.T, tracer *Tracer) {
+	t.Helper()
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID != "00f067aa0ba902b7" || sc.Flags != 1 {
+		t.Errorf("unexpected span context %+v", sc)
+	}
+	if sc.Traceparent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("traceparent should round trip, got %s", sc.Traceparent())
+	}
+
+	for _, invalid := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestHandleCreatesSpansFromTraceparent(t *testing.T) {
+	exporter := &recordingExporter{}
+	server := NewServer("test", "1.0", "test")
+	server.SetTracer(NewTracer(exporter))
+
+	var outgoing http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outgoing = r.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: NewTracingTransport(nil)}
+	server.RegisterTool(ToolDescription{
+		Name: "call_upstream",
+		Handler: func(r *http.Request, params map[string]any) (any, error) {
+			req, _ := http.NewRequestWithContext(r.Context(), "GET", upstream.URL, nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			resp.Body.Close()
+			return map[string]any{"ok": true}, nil
+		},
+	})
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set(TracestateHeader, "vendor=value")
+	if _, err := server.Handle(r, httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: MCPRequestParams{Name: "call_upstream"}}); err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+	flushSpans(t, server.Tracer)
+
+	if len(exporter.spans) != 2 {
+		t.Fatalf("expected tool and method spans, got %d", len(exporter.spans))
+	}
+	toolSpan, methodSpan := exporter.spans[0], exporter.spans[1]
+	if methodSpan.Name != "mcp tools/call" || methodSpan.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("method span should continue the remote trace, got %+v", methodSpan)
+	}
+	if toolSpan.ParentSpanID != methodSpan.SpanContext.SpanID || toolSpan.SpanContext.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("tool span should be a child of the method span, got %+v", toolSpan)
+	}
+
+	sc, err := ParseTraceparent(outgoing.Get(TraceparentHeader))
+	if err != nil {
+		t.Fatalf("outgoing call should carry a traceparent: %v", err)
+	}
+	if sc.SpanID != toolSpan.SpanContext.SpanID || outgoing.Get(TracestateHeader) != "vendor=value" {
+		t.Errorf("outgoing call should propagate the tool span, got %s %s", outgoing.Get(TraceparentHeader), outgoing.Get(TracestateHeader))
+	}
+}
+
+func TestTraceContextFromMeta(t *testing.T) {
+	exporter := &recordingExporter{}
+	server := NewServer("test", "1.0", "test")
+	server.SetTracer(NewTracer(exporter))
+
+	meta := map[string]any{TraceparentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	if _, err := server.Handle(httptest.NewRequest("POST", "/", nil), httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/list", Params: MCPRequestParams{Meta: meta}}); err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+	flushSpans(t, server.Tracer)
+	if len(exporter.spans) != 1 || exporter.spans[0].SpanContext.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected a span continuing the _meta trace, got %+v", exporter.spans)
+	}
+}
+
+func TestOTLPFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter := NewOTLPFileExporter(path, "mcp-test")
+	defer exporter.Close()
+	tracer := NewTracer(exporter)
+
+	_, span := tracer.Start(context.Background(), "work", SpanKindInternal, SpanContext{})
+	span.SetAttribute("count", 3)
+	span.End()
+	flushSpans(t, tracer)
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open span file: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		t.Fatal("expected one exported line")
+	}
+	var doc struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID    string `json:"traceId"`
+					Name       string `json:"name"`
+					Attributes []struct {
+						Key   string         `json:"key"`
+						Value map[string]any `json:"value"`
+					} `json:"attributes"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid OTLP/JSON: %v", err)
+	}
+	exported := doc.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if exported.Name != "work" || exported.TraceID != span.SpanContext.TraceID {
+		t.Errorf("unexpected exported span %+v", exported)
+	}
+	if exported.Attributes[0].Key != "count" || exported.Attributes[0].Value["intValue"] != "3" {
+		t.Errorf("unexpected attributes %+v", exported.Attributes)
+	}
+}
+
+// blockingExporter signals started and holds every export until release is closed
+type blockingExporter struct {
+	recordingExporter
+	started chan struct{}
+	release chan struct{}
+}
+
+func (e *blockingExporter) ExportSpans(ctx context.Context, spans []*Span) error {
+	e.started <- struct{}{}
+	<-e.release
+	return e.recordingExporter.ExportSpans(ctx, spans)
+}
+
+func TestTracerExportsInBackgroundBatches(t *testing.T) {
+	exporter := &blockingExporter{started: make(chan struct{}, 3), release: make(chan struct{})}
+	tracer := NewTracer(exporter)
+	tracer.BatchSize = 2
+	tracer.QueueSize = 4
+	tracer.FlushInterval = time.Hour
+	var dropped []error
+	tracer.OnExportError = func(err error) { dropped = append(dropped, err) }
+	end := func(count int) {
+		for range count {
+			_, span := tracer.Start(context.Background(), "work", SpanKindInternal, SpanContext{})
+			span.End()
+		}
+	}
+
+	// The first batch blocks in the exporter without blocking End, the queue takes four more spans and drops
+	// the last one
+	end(2)
+	<-exporter.started
+	end(5)
+	close(exporter.release)
+	flushSpans(t, tracer)
+
+	if len(exporter.spans) != 6 || exporter.batches != 3 {
+		t.Errorf("expected 6 spans in 3 batches, got %d spans in %d batches", len(exporter.spans), exporter.batches)
+	}
+	if len(dropped) != 1 || !errors.Is(dropped[0], ErrSpanQueueFull) {
+		t.Errorf("expected one dropped span, got %v", dropped)
+	}
+}
+
+func TestUnsampledTracesAreNotExported(t *testing.T) {
+	exporter := &recordingExporter{}
+	server := NewServer("test", "1.0", "test")
+	server.SetTracer(NewTracer(exporter))
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	if _, err := server.Handle(r, httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/list"}); err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+	flushSpans(t, server.Tracer)
+	if len(exporter.spans) != 0 {
+		t.Errorf("expected no spans for an unsampled trace, got %d", len(exporter.spans))
+	}
+}
+
+func TestSpanFlushTimeoutExportsEachRequest(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter)
+	tracer.FlushInterval = time.Hour
+	server := NewServer("test", "1.0", "test")
+	server.SetTracer(tracer)
+	server.SetSpanFlushTimeout(time.Second)
+
+	if _, err := server.Handle(httptest.NewRequest("POST", "/", nil), httptest.NewRecorder(), MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/list"}); err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+	if len(exporter.spans) != 1 {
+		t.Errorf("expected the request span to be exported before Handle returned, got %d spans", len(exporter.spans))
+	}
+}
+
+func TestTracerShutdown(t *testing.T) {
+	exporter := NewOTLPFileExporter(filepath.Join(t.TempDir(), "spans.jsonl"), "mcp-test")
+	tracer := NewTracer(exporter)
+	tracer.FlushInterval = time.Hour
+
+	_, span := tracer.Start(context.Background(), "work", SpanKindInternal, SpanContext{})
+	span.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if exporter.file != nil {
+		t.Error("expected Shutdown to close the exporter")
+	}
+	payload, err := os.ReadFile(exporter.Path)
+	if err != nil || len(payload) == 0 {
+		t.Errorf("expected the queued span to be exported on shutdown, got %q: %v", payload, err)
+	}
+
+	// Spans ending after shutdown are dropped without reopening the exporter
+	_, span = tracer.Start(context.Background(), "late", SpanKindInternal, SpanContext{})
+	span.End()
+	flushSpans(t, tracer)
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected a second Shutdown to do nothing, got %v", err)
+	}
+	if exporter.file != nil {
+		t.Error("expected late spans to be dropped")
+	}
+}
@@ -2,6 +2,7 @@
 package mcp
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/fredyk/westack-go/lambdas"
@@ -13,6 +14,22 @@ type MCPRequestParams struct {
 	Arguments map[string]any `json:"arguments"`
 	Meta      map[string]any `json:"_meta"`
 	StreamID  string         `json:"streamId,omitempty"`
+
+	// initialize
+	ProtocolVersion string         `json:"protocolVersion,omitempty"`
+	Capabilities    map[string]any `json:"capabilities,omitempty"`
+	ClientInfo      map[string]any `json:"clientInfo,omitempty"`
+
+	// logging/setLevel
+	Level string `json:"level,omitempty"`
+
+	// resources/read, resources/subscribe and resources/unsubscribe
+	URI string `json:"uri,omitempty"`
+
+	// completion/complete
+	Ref      *CompletionReference `json:"ref,omitempty"`
+	Argument *CompletionArgument  `json:"argument,omitempty"`
+	Context  *CompletionContext   `json:"context,omitempty"`
 }
 
 // MCPRequest represents a standard MCP protocol request
@@ -22,6 +39,10 @@ type MCPRequest struct {
 	ID      int              `json:"id"`
 	Method  string           `json:"method"`
 	Params  MCPRequestParams `json:"params"`
+
+	// Result and Error are set when the client answers a server-to-client request
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *JsonRPCError   `json:"error,omitempty"`
 }
 
 // ToolDescription represents an MCP tool description
@@ -29,8 +50,32 @@ type ToolDescription struct {
 	Name         string           `json:"name"`
 	Description  string           `json:"description"`
 	InputSchema  *openapi3.Schema `json:"inputSchema"`
-	OutputSchema *openapi3.Schema `json:"outputSchema"`
+	OutputSchema *openapi3.Schema `json:"outputSchema,omitempty"`
 	Raw          bool             `json:"raw,omitempty"`
+	// ReadOnly marks tools without side effects, only they can be called with GET through the REST endpoint
+	ReadOnly bool `json:"-"`
+	// RateLimit overrides the server rate limit for calls to this tool
+	RateLimit *RateLimit `json:"-"`
+	// Cache serves repeated calls with the same arguments from a cache, for idempotent tools
+	Cache *ToolCache `json:"-"`
+	// MaxResultBytes and ResultOverflow override the result limit of the server for this tool
+	MaxResultBytes int            `json:"-"`
+	ResultOverflow OverflowPolicy `json:"-"`
+
+	// revision is assigned by the registry to each registered definition
+	revision uint64
+	// mounted marks the gateway copies of tools, whose backend already limits their results
+	mounted bool
 
 	Handler func(r *http.Request, params map[string]any) (any, error) `json:"-"`
 }
+
+// MarshalJSON serializes the tool for MCP clients, with its schemas converted to JSON Schema 2020-12
+func (t ToolDescription) MarshalJSON() ([]byte, error) {
+	type tool ToolDescription
+	return json.Marshal(struct {
+		tool
+		InputSchema  map[string]any `json:"inputSchema"`
+		OutputSchema map[string]any `json:"outputSchema,omitempty"`
+	}{tool(t), JSONSchema(t.InputSchema), JSONSchema(t.OutputSchema)})
+}
@@ -1,6 +1,7 @@
 package mcpexamples
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 
@@ -11,15 +12,28 @@ import (
 var server *mcp.Server
 
 func init() {
-	server = chitamcputils.DefaultServer(chitamcputils.ServerOptions{
+	var err error
+	if server, err = setupServer(); err != nil {
+		panic(err)
+	}
+}
+
+// setupServer creates the server with its tools, failing when a tool cannot be registered
+func setupServer() (*mcp.Server, error) {
+	server := chitamcputils.DefaultServer(chitamcputils.ServerOptions{
 		Name:        "MCP Examples",
 		Version:     "1.0.0",
 		Description: "MCP Examples",
 		Debug:       true,
 	})
 
-	registerExampleSliceTool(server)
-	registerStandardSliceTool(server)
+	if err := registerExampleSliceTool(server); err != nil {
+		return nil, fmt.Errorf("failed to register example_slice: %w", err)
+	}
+	if err := registerStandardSliceTool(server); err != nil {
+		return nil, fmt.Errorf("failed to register standard_slice_tool: %w", err)
+	}
+	return server, nil
 }
 
 func ExamplesHandler(r *http.Request, w http.ResponseWriter, req mcp.MCPRequest) (io.ReadCloser, error) {
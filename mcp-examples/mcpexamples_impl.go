@@ -17,8 +17,8 @@ func exampleSliceHandler(r *http.Request, params map[string]any) (any, error) {
 	return data, nil
 }
 
-func registerExampleSliceTool(server *mcp.Server) {
-	server.RegisterTool(mcp.ToolDescription{
+func registerExampleSliceTool(server *mcp.Server) error {
+	return server.RegisterTool(mcp.ToolDescription{
 		Name:        "example_slice",
 		Raw:         true,
 		Description: "An example tool that returns a slice of items to demonstrate streaming.",
@@ -5,8 +5,8 @@ import (
 	"github.com/getkin/kin-openapi/openapi3"
 )
 
-func registerStandardSliceTool(server *mcp.Server) {
-	server.RegisterTool(mcp.ToolDescription{
+func registerStandardSliceTool(server *mcp.Server) error {
+	return server.RegisterTool(mcp.ToolDescription{
 		Name:        "standard_slice_tool",
 		Description: "An example tool that returns a slice of items to demonstrate standard streaming.",
 		InputSchema: &openapi3.Schema{
@@ -1,89 +1,42 @@
 package mcpexamples
 
 import (
-	"bufio"
-	"encoding/json"
-	"io"
-	"net/http"
-	"net/http/httptest"
-	"strings"
 	"testing"
 
 	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
-	"github.com/fredyk/westack-go/lambdas"
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp/mcptest"
 	"github.com/stretchr/testify/assert"
 )
 
-// MCPTestResponse is a helper struct for parsing MCP streaming responses in tests.
-type MCPTestResponse struct {
-	Jsonrpc string `json:"jsonrpc"`
-	Method  string `json:"method"`
-	Params  struct {
-		StreamID string `json:"streamId"`
-		Content  any    `json:"content"`
-	} `json:"params"`
-	Result any `json:"result"`
-	ID     int `json:"id"`
-}
-
-func executeMCPToolCall(t *testing.T, server *mcp.Server, toolName string, arguments map[string]any) *http.Response {
-	t.Helper()
-
-	req := mcp.MCPRequest{
-		JSONRPC: "2.0",
-		ID:      1,
-		Method:  "tools/call",
-		Params: mcp.MCPRequestParams{
-			Name:      toolName,
-			Arguments: arguments,
-		},
-	}
-
-	body, err := json.Marshal(req)
-	assert.NoError(t, err, "Failed to marshal request")
-
-	httpReq := httptest.NewRequest("POST", "/", strings.NewReader(string(body)))
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	w := httptest.NewRecorder()
-
-	req.LambdaRequest = lambdas.LambdaRequest{Payload: body}
-
-	respBody, err := server.Handle(httpReq, w, req)
-	assert.NoError(t, err, "Handler returned an error")
-	defer respBody.Close()
-
-	_, err = io.Copy(w.Body, respBody)
-	assert.NoError(t, err, "Failed to copy response body")
-
-	resp := w.Result()
-	assert.Equal(t, http.StatusOK, resp.StatusCode, "Expected status code 200")
-
-	return resp
-}
-
 func TestRawSliceStreaming(t *testing.T) {
-	// Create a new server
 	server := mcp.NewServer("test-server", "1.0", "Test Server")
-	registerExampleSliceTool(server)
+	assert.NoError(t, registerExampleSliceTool(server), "Failed to register example_slice")
 
-	// Execute the tool call using the helper
-	resp := executeMCPToolCall(t, server, "example_slice", map[string]any{})
+	// The example_slice tool streams one progress notification per item before the result
+	var result struct {
+		Items []map[string]any `json:"items"`
+	}
+	mcptest.New(t, server).CallTool("example_slice", map[string]any{}).AssertOK().AssertProgress(3).Decode(&result)
+	assert.Len(t, result.Items, 3, "Expected the 3 items in the result")
+}
 
-	// Verify the SSE stream
-	scanner := bufio.NewScanner(resp.Body)
-	defer resp.Body.Close()
+func TestConformance(t *testing.T) {
+	mcptest.Conformance(t, server,
+		mcptest.WithToolCall("example_slice", nil),
+		mcptest.WithToolCall("standard_slice_tool", nil),
+	)
+}
 
-	var eventCount int
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "data:") {
-			eventCount++
+func TestToolSchemasAreJSONSchema(t *testing.T) {
+	for _, tool := range mcptest.New(t, server).ListTools() {
+		if tool.InputSchema["$schema"] != mcp.JSONSchemaDialect || tool.OutputSchema["$schema"] != mcp.JSONSchemaDialect {
+			t.Errorf("%s: schemas must declare the JSON Schema dialect", tool.Name)
+		}
+		if tool.Name == "example_slice" {
+			variants := tool.OutputSchema["oneOf"].([]any)
+			if len(variants) != 2 || variants[0].(map[string]any)["required"].([]any)[0] != "count" {
+				t.Errorf("unexpected oneOf %v", variants)
+			}
 		}
 	}
-
-	assert.NoError(t, scanner.Err(), "Error reading response body")
-
-	// The example_slice tool returns a slice with 3 items
-	assert.Equal(t, 3, eventCount, "Expected 3 SSE events for raw streaming")
 }
@@ -1,124 +1,181 @@
 package mcp_hour
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp/mcptest"
+	"github.com/getkin/kin-openapi/openapi3"
 )
 
-// testResponseWriter is a mock http.ResponseWriter for testing
-type testResponseWriter struct {
-	header     http.Header
-	statuscode int
-	body       strings.Builder
-}
+func TestHandler(t *testing.T) {
+	var response HourResponse
+	mcptest.New(t, server).Send(mcptest.NewRequest(1, "", mcp.MCPRequestParams{})).AssertOK().DecodeResult(&response)
 
-func (w *testResponseWriter) Header() http.Header {
-	return w.header
-}
+	// Verify the response structure
+	if response.Hour < 1 || response.Hour > 12 {
+		t.Errorf("Hour should be between 1 and 12, got %d", response.Hour)
+	}
 
-func (w *testResponseWriter) Write(b []byte) (int, error) {
-	return w.body.Write(b)
+	if response.AmPm != "AM" && response.AmPm != "PM" {
+		t.Errorf("AmPm should be either AM or PM, got %s", response.AmPm)
+	}
+
+	// Check if the message contains the hour and AM/PM
+	expectedMessagePart := "Current hour is "
+	if !strings.HasPrefix(response.Message, expectedMessagePart) {
+		t.Errorf("Message should start with '%s', got '%s'", expectedMessagePart, response.Message)
+	}
+
+	// Ensure the currentTime string is a valid RFC3339 timestamp
+	if _, err := time.Parse(time.RFC3339, response.CurrentTime); err != nil {
+		t.Errorf("Expected currentTime to be a valid RFC3339 timestamp, but got error: %v", err)
+	}
 }
 
-func (w *testResponseWriter) WriteHeader(statusCode int) {
-	w.statuscode = statusCode
+func TestPingReturnsEmptyResult(t *testing.T) {
+	var result map[string]any
+	mcptest.New(t, server).Call("ping", mcp.MCPRequestParams{}).AssertOK().DecodeResult(&result)
+	if len(result) != 0 {
+		t.Errorf("expected an empty result, got %v", result)
+	}
 }
 
-// parseSSEEvent parses an SSE event data line into a HourResponse
-func parseSSEEvent(dataLine string) (HourResponse, error) {
-	// SSE data lines are prefixed with "data: "
-	dataLine = strings.TrimPrefix(dataLine, "data: ")
+func TestConformance(t *testing.T) {
+	mcptest.Conformance(t, server, mcptest.WithToolCall("get_time", map[string]any{"timezone": "UTC"}))
+}
 
-	// Try parsing JSON-RPC 2.0 response format where hour data is in 'result' field
-	var jsonRpcResp struct {
-		JsonRPC string       `json:"jsonrpc"`
-		ID      int          `json:"id"`
-		Result  HourResponse `json:"result"`
+func TestToolSchemasAreJSONSchema(t *testing.T) {
+	tools := mcptest.New(t, server).ListTools()
+	if len(tools) != 1 || tools[0].Name != "get_time" {
+		t.Fatalf("unexpected tools %v", tools)
 	}
-
-	err := json.Unmarshal([]byte(dataLine), &jsonRpcResp)
-	if err == nil && (jsonRpcResp.Result.Hour > 0 || jsonRpcResp.Result.AmPm != "") {
-		return jsonRpcResp.Result, nil
+	for field, schema := range map[string]map[string]any{"inputSchema": tools[0].InputSchema, "outputSchema": tools[0].OutputSchema} {
+		if schema["$schema"] != mcp.JSONSchemaDialect || schema["type"] != "object" {
+			t.Errorf("%s is not a JSON Schema object: %v", field, schema)
+		}
 	}
-
-	// Try direct format (for backward compatibility)
-	var directResp HourResponse
-	err = json.Unmarshal([]byte(dataLine), &directResp)
-	if err == nil && (directResp.Hour > 0 || directResp.AmPm != "") {
-		return directResp, nil
+	hour := tools[0].OutputSchema["properties"].(map[string]any)["hour"].(map[string]any)
+	if hour["type"] != "integer" || hour["$schema"] != nil {
+		t.Errorf("unexpected hour property %v", hour)
 	}
-
-	return HourResponse{}, fmt.Errorf("failed to parse SSE event: %s", dataLine)
 }
 
-func TestHandler(t *testing.T) {
-	// Create a dummy request
-	req, err := http.NewRequest("GET", "/sse", nil)
+func TestOpenAPIDocument(t *testing.T) {
+	options := serverOptions
+	options.RESTBasePath = "/"
+	options.OpenAPIPath = mcp.DefaultOpenAPIPath
+	server, err := setupServer(options)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// Create a fake ResponseWriter for testing
-	w := &testResponseWriter{header: make(http.Header)}
-
-	// Call the handler which returns an io.ReadCloser
-	resp, err := Handler(req, w, mcp.MCPRequest{})
+	r := httptest.NewRequest(http.MethodGet, mcp.DefaultOpenAPIPath+".json", nil)
+	body, err := server.Handle(r, httptest.NewRecorder(), mcp.MCPRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	doc, err := openapi3.NewLoader().LoadFromIoReader(body)
 	if err != nil {
-		t.Fatalf("Handler returned error: %v", err)
+		t.Fatalf("expected the document on %s: %v", mcp.DefaultOpenAPIPath, err)
 	}
-
-	// Verify we got an io.ReadCloser back
-	if resp == nil {
-		t.Fatal("Handler returned nil response")
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+	operation := doc.Paths.Find("/tools/get_time")
+	if operation == nil || operation.Post == nil {
+		t.Fatalf("expected a POST operation for get_time")
 	}
 
-	// Make sure we close the reader when done
-	defer resp.Close()
-
-	// Parse the response as a HourResponse using our parseSSEEvent function
-	scanner := bufio.NewScanner(resp)
-	var lines []string
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "data: ") {
-			lines = append(lines, line)
-		}
+	// The advertised operation reaches the tool rather than the JSON-RPC default handler
+	w := httptest.NewRecorder()
+	body, err = server.Handle(httptest.NewRequest(http.MethodPost, "/tools/get_time", strings.NewReader(`{"timezone": "UTC"}`)), w, mcp.MCPRequest{})
+	if err != nil {
+		t.Fatal(err)
 	}
+	defer body.Close()
+	var hour HourResponse
+	if err := json.NewDecoder(body).Decode(&hour); err != nil || w.Code != http.StatusOK || hour.CurrentTime == "" {
+		t.Errorf("expected the plain get_time result, got %d %+v: %v", w.Code, hour, err)
+	}
+}
 
-	if len(lines) == 0 {
-		t.Fatal("No data lines found in SSE response")
+func TestRESTEndpoint(t *testing.T) {
+	options := serverOptions
+	options.RESTBasePath = "/"
+	server, err := setupServer(options)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	// Parse the first data line as a HourResponse
-	response, err := parseSSEEvent(lines[0])
+	r := httptest.NewRequest(http.MethodGet, "/tools/get_time?timezone=UTC", nil)
+	w := httptest.NewRecorder()
+	body, err := server.Handle(r, w, mcp.MCPRequest{})
 	if err != nil {
-		t.Fatalf("Failed to parse SSE event: %v\nLine content: %s", err, lines[0])
+		t.Fatal(err)
 	}
+	defer body.Close()
 
-	// Verify the response structure
-	if response.Hour < 1 || response.Hour > 12 {
-		t.Errorf("Hour should be between 1 and 12, got %d", response.Hour)
+	var hour HourResponse
+	if err := json.NewDecoder(body).Decode(&hour); err != nil {
+		t.Fatalf("expected a plain JSON result: %v", err)
 	}
+	if w.Code != http.StatusOK || hour.CurrentTime == "" || hour.Error != "" {
+		t.Errorf("unexpected response %d %+v", w.Code, hour)
+	}
+}
 
-	if response.AmPm != "AM" && response.AmPm != "PM" {
-		t.Errorf("AmPm should be either AM or PM, got %s", response.AmPm)
+func TestRegisterGetTimeToolReportsErrors(t *testing.T) {
+	server := mcp.NewServer("test", "1.0", "test")
+	if err := registerGetTimeTool(server); err != nil {
+		t.Fatalf("registerGetTimeTool failed: %v", err)
 	}
+	if err := registerGetTimeTool(server); !errors.Is(err, mcp.ErrToolExists) {
+		t.Errorf("expected a duplicate registration to fail with ErrToolExists, got %v", err)
+	}
+}
 
-	// Check if the message contains the hour and AM/PM
-	expectedMessagePart := "Current hour is "
-	if response.Message[:len(expectedMessagePart)] != expectedMessagePart {
-		t.Errorf("Message should start with '%s', got '%s'", expectedMessagePart, response.Message)
+func TestTimezoneCompletionAndHourResource(t *testing.T) {
+	h := mcptest.New(t, server)
+	h.Initialize(nil)
+
+	var completed struct {
+		Completion mcp.CompletionResult `json:"completion"`
+	}
+	h.Call("completion/complete", mcp.MCPRequestParams{
+		Ref:      &mcp.CompletionReference{Type: mcp.RefResource, URI: hourTemplate},
+		Argument: &mcp.CompletionArgument{Name: "timezone", Value: "mad"},
+	}).AssertOK().DecodeResult(&completed)
+	if len(completed.Completion.Values) != 1 || completed.Completion.Values[0] != "Europe/Madrid" {
+		t.Errorf("expected Europe/Madrid, got %v", completed.Completion.Values)
 	}
 
-	// Ensure the currentTime string is a valid RFC3339 timestamp
-	if _, err := time.Parse(time.RFC3339, response.CurrentTime); err != nil {
-		t.Errorf("Expected currentTime to be a valid RFC3339 timestamp, but got error: %v", err)
+	var read struct {
+		Contents []mcp.ResourceContents `json:"contents"`
+	}
+	h.Call("resources/read", mcp.MCPRequestParams{URI: "hour://Europe/Madrid"}).AssertOK().DecodeResult(&read)
+	var hour HourResponse
+	if len(read.Contents) != 1 || json.Unmarshal([]byte(read.Contents[0].Text), &hour) != nil || hour.CurrentTime == "" {
+		t.Errorf("expected the hour in Europe/Madrid, got %+v", read.Contents)
+	}
+}
+
+func TestDefaultHandlerOnlyAnswersUnnamedRequests(t *testing.T) {
+	h := mcptest.New(t, server)
+
+	var hour HourResponse
+	h.Send(mcptest.NewRequest(1, "", mcp.MCPRequestParams{Arguments: map[string]any{"timezone": "UTC"}})).AssertOK().DecodeResult(&hour)
+	if hour.AmPm != "AM" && hour.AmPm != "PM" {
+		t.Errorf("expected the hour of the default handler, got %+v", hour)
 	}
+
+	h.Call("get_hour", mcp.MCPRequestParams{Arguments: map[string]any{"timezone": "UTC"}}).AssertError(mcp.ErrMethodNotFound)
 }
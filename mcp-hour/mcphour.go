@@ -1,6 +1,7 @@
 package mcp_hour
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 
@@ -31,21 +32,37 @@ func (hr HourResponse) ToMap() map[string]any {
 
 var server *mcp.Server
 
+// serverOptions configures the lambda server, tests derive other configurations from it
+var serverOptions = chitamcputils.ServerOptions{
+	Name:        "HourMCP",
+	Version:     "1.0.0",
+	Description: "MCP server that provides current timezone",
+	Debug:       true,
+}
+
 // Handler is the lambda entry point for Chita Cloud
 func Handler(r *http.Request, w http.ResponseWriter, req mcp.MCPRequest) (io.ReadCloser, error) {
 	return server.Handle(r, w, req)
 }
 
 func init() {
-	// Create server with tools
-	server = chitamcputils.DefaultServer(chitamcputils.ServerOptions{
-		Name:        "HourMCP",
-		Version:     "1.0.0",
-		Description: "MCP server that provides current timezone",
-		Debug:       true,
-	})
+	var err error
+	if server, err = setupServer(serverOptions); err != nil {
+		panic(err)
+	}
+}
 
-	registerGetTimeTool(server)
+// setupServer creates a server with its tools and resources, failing when one cannot be registered
+func setupServer(options chitamcputils.ServerOptions) (*mcp.Server, error) {
+	server := chitamcputils.CreateMCPServer(options)
+
+	if err := registerGetTimeTool(server); err != nil {
+		return nil, fmt.Errorf("failed to register get_time: %w", err)
+	}
+	if err := registerHourResource(server); err != nil {
+		return nil, fmt.Errorf("failed to register the hour resource: %w", err)
+	}
 
 	registerDefaultHandler(server)
+	return server, nil
 }
@@ -1,6 +1,7 @@
 package mcp_hour
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -43,10 +44,11 @@ func getHourInfo(timezone string) (HourResponse, error) {
 	}, nil
 }
 
-func registerGetTimeTool(server *mcp.Server) {
-	server.RegisterTool(mcp.ToolDescription{
+func registerGetTimeTool(server *mcp.Server) error {
+	return server.RegisterTool(mcp.ToolDescription{
 		Name:        "get_time",
 		Description: "Get the current timestamp in the specified timezone",
+		ReadOnly:    true,
 		InputSchema: &openapi3.Schema{
 			Type: &openapi3.Types{openapi3.TypeObject},
 			Properties: map[string]*openapi3.SchemaRef{
@@ -85,6 +87,45 @@ func registerGetTimeTool(server *mcp.Server) {
 	})
 }
 
+// hourTemplate is the resource template exposing the current hour of a timezone
+const hourTemplate = "hour://{timezone}"
+
+// commonTimezones are the completion candidates of the timezone variable
+var commonTimezones = []string{
+	"UTC",
+	"America/New_York", "America/Chicago", "America/Denver", "America/Los_Angeles", "America/Mexico_City",
+	"America/Bogota", "America/Sao_Paulo", "America/Buenos_Aires", "America/Toronto",
+	"Europe/London", "Europe/Madrid", "Europe/Paris", "Europe/Berlin", "Europe/Rome", "Europe/Lisbon",
+	"Europe/Amsterdam", "Europe/Moscow", "Africa/Cairo", "Africa/Johannesburg", "Africa/Lagos",
+	"Asia/Dubai", "Asia/Kolkata", "Asia/Shanghai", "Asia/Singapore", "Asia/Tokyo", "Asia/Seoul",
+	"Australia/Sydney", "Pacific/Auckland",
+}
+
+// registerHourResource exposes the hour of any timezone as hour://{timezone}, completing timezone names
+func registerHourResource(server *mcp.Server) error {
+	err := server.RegisterResourceTemplate(mcp.ResourceTemplate{
+		URITemplate: hourTemplate,
+		Name:        "Current hour",
+		Description: "Current hour in the given timezone",
+		MimeType:    "application/json",
+		Handler: func(r *http.Request, uri string, variables map[string]string) ([]mcp.ResourceContents, error) {
+			hourInfo, err := getHourInfo(variables["timezone"])
+			if err != nil {
+				return nil, err
+			}
+			payload, err := json.Marshal(hourInfo.ToMap())
+			if err != nil {
+				return nil, err
+			}
+			return []mcp.ResourceContents{{Text: string(payload)}}, nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return server.RegisterResourceCompletion(hourTemplate, "timezone", mcp.CompleteFrom(commonTimezones...))
+}
+
 func registerDefaultHandler(server *mcp.Server) {
 	server.SetDefaultHandler(func(r *http.Request, params map[string]any) (any, error) {
 		return getFormattedHourInfo(r, params)
@@ -103,6 +144,6 @@ func getFormattedHourInfo(r *http.Request, params map[string]any) (any, error) {
 		return nil, err
 	}
 
-	fmt.Println("Sending get_hour response:", hourInfo)
+	mcp.LoggerFromContext(r.Context()).Debug("Sending get_hour response", "hour", hourInfo.Hour, "amPm", hourInfo.AmPm)
 	return hourInfo.ToMap(), nil
 }
@@ -1,11 +1,14 @@
 package sendexpopush
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
 )
 
 func TestSendExpoPush_Success(t *testing.T) {
@@ -74,6 +77,33 @@ func TestSendExpoPush_EmptyToken(t *testing.T) {
 	}
 }
 
+func TestSendExpoPushContext_UsesRequestContext(t *testing.T) {
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"status": "ok", "id": "1"}})
+	}))
+	defer ts.Close()
+
+	os.Setenv("EXPO_PUSH_BASE_URL", ts.URL)
+	defer os.Unsetenv("EXPO_PUSH_BASE_URL")
+
+	ctx, span := mcp.NewTracer(nil).Start(context.Background(), "send push", mcp.SpanKindInternal, mcp.SpanContext{})
+	if _, err := SendExpoPushContext(ctx, Request{To: "ExpoPushToken[xxxxxxxxxxxxxxxxxxxxxx]"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received != span.SpanContext.Traceparent() {
+		t.Fatalf("expected traceparent %s, got %q", span.SpanContext.Traceparent(), received)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := SendExpoPushContext(cancelled, Request{To: "ExpoPushToken[xxxxxxxxxxxxxxxxxxxxxx]"}); err == nil {
+		t.Fatal("expected an error for a cancelled context")
+	}
+}
+
 func TestSendExpoPush_Integration(t *testing.T) {
 	// Only run if a real token is provided
 	pushToken := os.Getenv("EXPO_INTEGRATION_PUSH_TOKEN")
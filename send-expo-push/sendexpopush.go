@@ -2,6 +2,7 @@ package sendexpopush
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/chitacloud/lambda-examples/chitacloud-utils/lib/mcp"
 )
 
 // Request represents the input for sending an Expo push notification.
@@ -35,7 +38,8 @@ type Response struct {
 }
 
 var (
-	defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+	// The tracing transport propagates the trace context of SendExpoPushContext to the Expo API
+	defaultHTTPClient = &http.Client{Timeout: 10 * time.Second, Transport: mcp.NewTracingTransport(nil)}
 )
 
 // getBaseURL returns the Expo Push API base URL. Overridable via EXPO_PUSH_BASE_URL for tests.
@@ -62,6 +66,12 @@ func validateToken(token string) error {
 //
 // ChitaCloud handler style: Accepts a typed Request and returns a typed Response and error.
 func SendExpoPush(req Request) (Response, error) {
+	return SendExpoPushContext(context.Background(), req)
+}
+
+// SendExpoPushContext is like SendExpoPush but issues the HTTP call with ctx,
+// which carries cancellation and the trace context of the calling tool handler.
+func SendExpoPushContext(ctx context.Context, req Request) (Response, error) {
 	// Validate token lightly; if format mismatch we still allow sending after warning
 	if err := validateToken(req.To); err != nil {
 		// If token is empty or clearly invalid, abort. For prefix mismatch, we'll still attempt.
@@ -94,7 +104,7 @@ func SendExpoPush(req Request) (Response, error) {
 	}
 
 	url := getBaseURL() + "/--/api/v2/push/send"
-	httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
 	if err != nil {
 		return Response{Status: "error", Message: "failed to create http request"}, err
 	}